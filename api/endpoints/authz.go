@@ -1,6 +1,7 @@
 package endpoints
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -46,13 +47,25 @@ func SwapAuthz(context *gin.Context) {
 		return
 	}
 
-	txClient, err := osmosis.GetOsmosisTxClient(conf.Api.ChainID, conf.GetApiRpcSubmitTxEndpoint(), conf.Api.KeyringHomeDir, conf.Api.KeyringBackend, conf.Api.HotWalletKey)
+	//Grants issued before a key rotation name the retiring hot wallet, so that key must execute the swap
+	txClient, err := osmosis.GetOsmosisTxClient(conf.Api.ChainID, conf.GetApiRpcSubmitTxEndpoint(), conf.Api.KeyringHomeDir, conf.Api.KeyringBackend, conf.GetHotWalletKey(jwtClaims.GranteeAddress()))
 	if err != nil {
 		config.Logger.Error("GetOsmosisTxClient", zap.Error(err))
 		context.JSON(http.StatusInternalServerError, "server misconfiguration (query client error), please notify administrator")
 		return
 	}
 
+	//The user's grant only allows one swap type (MsgSwapExactAmountIn or MsgSwapExactAmountOut)
+	requestMsgType := types.MsgTypeURL(&gamm.MsgSwapExactAmountIn{})
+	if request.SimulatedUserSwap.IsExactAmountOut() {
+		requestMsgType = types.MsgTypeURL(&gamm.MsgSwapExactAmountOut{})
+	}
+	if jwtClaims.GrantType != "" && jwtClaims.GrantType != requestMsgType {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "authz grant does not allow " + requestMsgType})
+		context.Abort()
+		return
+	}
+
 	//Get user token balances
 	userBalances, err := osmosis.GetAccountBalances(txClient, jwtUserAddress)
 	if err != nil {
@@ -62,16 +75,59 @@ func SwapAuthz(context *gin.Context) {
 	}
 
 	// Make sure the user's wallet has the requisite funds to do the swap
-	balanceOk := osmosis.HasTokens(request.SimulatedUserSwap.TokenIn, userBalances)
+	balanceOk := osmosis.HasTokens(request.SimulatedUserSwap.GetTokenIn(), userBalances)
 	if !balanceOk {
 		config.Logger.Info("Insufficient balance",
 			zap.String("user address", jwtUserAddress),
-			zap.String("token in", request.SimulatedUserSwap.TokenIn.String()),
+			zap.String("token in", request.SimulatedUserSwap.GetTokenIn().String()),
 		)
 		context.JSON(http.StatusBadRequest, "Insufficient balance")
 		return
 	}
 
+	//Never trust the client's simulated amounts (or the height it claims they were simulated at), recompute them from the current pool state
+	request.SimulationHeight = 0
+	err = osmosis.VerifySimulation(txClient, &request)
+	if errors.Is(err, osmosis.ErrPoolDataUnavailable) {
+		config.Logger.Error("VerifySimulation", zap.Error(err))
+		context.JSON(http.StatusInternalServerError, "Internal RPC query failed, retry later")
+		return
+	} else if err != nil {
+		config.Logger.Info("Simulation rejected", zap.String("user address", jwtUserAddress), zap.Error(err))
+		context.JSON(http.StatusBadRequest, "simulation does not match current pool state, simulate again")
+		return
+	}
+
+	//Only trade through pools and denoms the route policy allows
+	err = osmosis.CheckPolicy(txClient, &request)
+	if errors.Is(err, simulator.ErrPolicyViolation) {
+		config.Logger.Info("Simulation rejected by policy", zap.String("user address", jwtUserAddress), zap.Error(err))
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		config.Logger.Error("CheckPolicy", zap.Error(err))
+		context.JSON(http.StatusInternalServerError, "could not check route policy, retry later")
+		return
+	}
+
+	//Our own route search may find arbitrage the client didn't (or a better one)
+	err = osmosis.FindArbitrage(txClient, &request)
+	if err != nil {
+		config.Logger.Warn("FindArbitrage", zap.Error(err))
+	}
+
+	//The pools may have moved since the client simulated the swap
+	err = osmosis.RefreshSimulation(txClient, &request)
+	if errors.Is(err, osmosis.ErrStaleSimulation) {
+		config.Logger.Info("Stale simulation rejected", zap.String("user address", jwtUserAddress), zap.Error(err))
+		context.JSON(http.StatusBadRequest, "arbitrage no longer available at current pool state, request a new quote")
+		return
+	} else if err != nil {
+		config.Logger.Error("RefreshSimulation", zap.Error(err))
+		context.JSON(http.StatusInternalServerError, "Internal RPC query failed, retry later")
+		return
+	}
+
 	msgs, gas, err := buildSwaps(txClient, request)
 	if err != nil {
 		config.Logger.Error("buildSwaps", zap.Error(err))
@@ -83,7 +139,10 @@ func SwapAuthz(context *gin.Context) {
 	}
 
 	res, txB, err := submitTx(txClient, msgs, gas)
-	if err != nil && res == nil {
+	if errors.Is(err, osmosis.ErrSequencesHeld) {
+		context.JSON(http.StatusServiceUnavailable, "hot wallet is waiting for a Zenith auction, please retry after the next block")
+		return
+	} else if err != nil && res == nil {
 		context.JSON(http.StatusBadRequest, "failed to submit trade via RPC")
 		return
 	} else if err != nil && res != nil {
@@ -91,7 +150,7 @@ func SwapAuthz(context *gin.Context) {
 		return
 	}
 
-	id, err := api.AddAuthzTxSet([][]byte{txB}, &request, txClient.TxConfig.TxDecoder(), request.UserAddress, config.HotWalletAddress)
+	id, err := api.AddAuthzTxSet([][]byte{txB}, &request, txClient.TxConfig.TxDecoder(), request.UserAddress, txClient.GetFromAddress().String())
 	if err != nil {
 		fmt.Println("Tracking info may be unavailable for TX set due to unexpected error " + err.Error())
 	}
@@ -102,6 +161,19 @@ func SwapAuthz(context *gin.Context) {
 }
 
 func buildUserSwap(simulatedUserSwap *simulator.SimulatedSwap, address string) types.Msg {
+	if simulatedUserSwap.IsExactAmountOut() {
+		tokenOut := simulatedUserSwap.TokenOut
+		tokenInMaxAmt := simulatedUserSwap.TokenInMaxAmount
+		routes := simulatedUserSwap.OutRoutes
+
+		fmt.Printf("Authz requested with user swap: Token out: %s. Maximum amount in: %s. Pool(s) %s.\n",
+			tokenOut,
+			tokenInMaxAmt,
+			simulatedUserSwap.Pools)
+
+		return osmosis.BuildSwapExactAmountOut(tokenOut, tokenInMaxAmt, routes, address)
+	}
+
 	tokenIn := simulatedUserSwap.TokenIn
 	tokenOutMinAmt := simulatedUserSwap.TokenOutMinAmount
 	routes := simulatedUserSwap.Routes
@@ -123,12 +195,7 @@ func submitTx(
 	msgs []types.Msg,
 	txGas uint64,
 ) (*types.TxResponse, []byte, error) {
-	txBytes, err := osmosis.GetSignedTx(txClient, msgs, txGas)
-	if err != nil {
-		return nil, nil, err
-	}
-	r, e := txClient.BroadcastTxSync(txBytes)
-	return r, txBytes, e
+	return osmosis.SignSubmitTxBytes(txClient, msgs, txGas)
 }
 
 func buildSwaps(
@@ -138,33 +205,42 @@ func buildSwaps(
 	msgs = []types.Msg{}
 	msgUserSwap := buildUserSwap(swapRequest.SimulatedUserSwap, swapRequest.UserAddress)
 
-	swapMsg := msgUserSwap.(*gamm.MsgSwapExactAmountIn)
-	userSwapMsgBytes, mErr := swapMsg.Marshal()
-	if mErr != nil {
-		return
+	userSwapAny, err := ctypes.NewAnyWithValue(msgUserSwap)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	//txClient should be associated with the hot wallet, so this is using the hot wallet to do a trade for the user
 	msgExec := &authz.MsgExec{
 		Grantee: txClient.GetFromAddress().String(),
-		Msgs:    []*ctypes.Any{{TypeUrl: "/osmosis.gamm.v1beta1.MsgSwapExactAmountIn", Value: userSwapMsgBytes}},
+		Msgs:    []*ctypes.Any{userSwapAny},
 	}
 
 	msgs = append(msgs, msgExec)
-	gasNeeded = getGasFee(len(swapRequest.SimulatedUserSwap.Routes))
+	gasNeeded = getGasFee(swapRequest.SimulatedUserSwap.NumRoutes())
 
 	// It wouldn't make a lot of sense to use the authz request endpoint if there isn't arbitrage.
 	// However, it is allowed to do so.
-	if swapRequest.HasArbitrageOpportunity {
+	arbSwaps := []*simulator.SimulatedSwap{}
+	arbGasNeeded := uint64(0)
+	for _, arbSwap := range swapRequest.GetArbitrageSwaps() {
 		fmt.Printf("Authz requested with arbitrage swap: Token in: %s. Pool(s) %s.\n",
-			swapRequest.ArbitrageSwap.SimulatedSwap.TokenIn.String(), swapRequest.ArbitrageSwap.SimulatedSwap.Pools)
+			arbSwap.SimulatedSwap.GetTokenIn().String(), arbSwap.SimulatedSwap.Pools)
+		arbSwaps = append(arbSwaps, arbSwap.SimulatedSwap)
+		arbGasNeeded = arbGasNeeded + getGasFee(arbSwap.SimulatedSwap.NumRoutes())
+	}
 
-		arbSwaps, err := osmosis.BuildArbitrageSwap(txClient, swapRequest.ArbitrageSwap.SimulatedSwap.TokenIn, swapRequest.ArbitrageSwap.SimulatedSwap.Routes)
-		if err != nil {
+	if len(arbSwaps) > 0 {
+		arbMsgs, err := osmosis.BuildArbitrages(txClient, swapRequest.SimulatedUserSwap, arbSwaps)
+		if errors.Is(err, simulator.ErrUnprofitableArbitrage) {
+			//None of the arbitrage is profitable anymore, the user's swap still goes through on its own
+			fmt.Printf("Authz arbitrage skipped: %s\n", err.Error())
+		} else if err != nil {
 			return nil, 0, err
+		} else {
+			msgs = append(msgs, arbMsgs...)
+			gasNeeded = gasNeeded + arbGasNeeded
 		}
-		msgs = append(msgs, arbSwaps...)
-		gasNeeded = gasNeeded + getGasFee(len(swapRequest.ArbitrageSwap.SimulatedSwap.Routes))
 	}
 
 	return
@@ -14,6 +14,7 @@ import (
 	"github.com/cosmos/cosmos-sdk/x/authz"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	gamm "github.com/osmosis-labs/osmosis/v13/x/gamm/types"
 	"go.uber.org/zap"
 )
 
@@ -23,11 +24,17 @@ type TokenRequest struct {
 }
 
 type AuthzGranteeResponse struct {
-	GranteeAddress string `json:"authz_grantee"`
+	GranteeAddress           string   `json:"authz_grantee"`           //New grants should always be issued to this address
+	GranteeAddresses         []string `json:"authz_grantees"`          //All grantees we accept new grants for
+	DrainingGranteeAddresses []string `json:"authz_grantees_draining"` //Existing grants to these grantees still work, but new grants aren't accepted
 }
 
 func AuthzGranteeInfo(context *gin.Context) {
-	context.JSON(http.StatusOK, &AuthzGranteeResponse{GranteeAddress: config.HotWalletAddress})
+	draining := []string{}
+	if api.RetiringGranteeDraining() {
+		draining = append(draining, config.RetiringHotWalletAddress)
+	}
+	context.JSON(http.StatusOK, &AuthzGranteeResponse{GranteeAddress: config.HotWalletAddress, GranteeAddresses: []string{config.HotWalletAddress}, DrainingGranteeAddresses: draining})
 }
 
 // Verifies a user's identity through a valid, signed authz grant. Note: considering cosmos-sdk/MsgVerifyInvariant instead.
@@ -136,7 +143,7 @@ func GenerateToken(context *gin.Context) {
 	secondsUntilGrantExpires := time.Until(authzGrant.Grant.Expiration).Seconds()
 
 	//TODO: need to test and make sure the type starts with a slash, but I think so based on the CLI command I tested.
-	if grantType != "/osmosis.gamm.v1beta1.MsgSwapExactAmountIn" {
+	if grantType != types.MsgTypeURL(&gamm.MsgSwapExactAmountIn{}) && grantType != types.MsgTypeURL(&gamm.MsgSwapExactAmountOut{}) {
 		config.Logger.Error("TX is not an authz grant", zap.String("cosmos TX", "Invalid grant authorization"))
 		context.JSON(http.StatusBadRequest, "authz grant is not valid")
 		return
@@ -150,7 +157,7 @@ func GenerateToken(context *gin.Context) {
 		return
 	}
 
-	tokenString, err := GenerateJWT(authzGrant.Grant.Expiration, request.Address)
+	tokenString, err := GenerateJWT(authzGrant.Grant.Expiration, request.Address, authzGrant.Grantee, grantType)
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		context.Abort()
@@ -159,8 +166,10 @@ func GenerateToken(context *gin.Context) {
 	context.JSON(http.StatusOK, gin.H{"token": tokenString})
 }
 
-func GenerateJWT(expirationTime time.Time, address string) (tokenString string, err error) {
+func GenerateJWT(expirationTime time.Time, address string, grantee string, grantType string) (tokenString string, err error) {
 	claims := &api.JWTClaim{
+		Grantee:   grantee,
+		GrantType: grantType,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   address,
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
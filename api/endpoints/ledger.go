@@ -0,0 +1,18 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/DefiantLabs/RedpointSwap/api"
+	"github.com/gin-gonic/gin"
+)
+
+// Every sweep from the hot wallet to the cold wallet, most recent first
+func GetSweeps(context *gin.Context) {
+	context.JSON(http.StatusOK, api.GetSweeps())
+}
+
+// Every arbitrage TX the mempool monitor submitted after a pending swap, most recent first
+func GetBackruns(context *gin.Context) {
+	context.JSON(http.StatusOK, api.GetBackruns())
+}
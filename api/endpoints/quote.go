@@ -0,0 +1,71 @@
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/DefiantLabs/RedpointSwap/osmosis"
+	"github.com/DefiantLabs/RedpointSwap/simulator"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Default slippage for quotes that don't specify one (1%)
+const defaultQuoteSlippage = 0.01
+
+// Finds the best route for a user swap with our own pool math. The response is a complete simulation that can be
+// submitted to /api/zenith or /api/secured/authz. Query params: tokenIn (e.g. 1000000uosmo), denomOut, slippage (optional, e.g. 0.01), address (optional).
+func GetQuote(context *gin.Context) {
+	tokenIn, err := sdk.ParseCoinNormalized(context.Query("tokenIn"))
+	if err != nil || !tokenIn.Amount.IsPositive() {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "tokenIn must be an amount and denom, e.g. 1000000uosmo"})
+		return
+	}
+
+	denomOut := context.Query("denomOut")
+	if err := sdk.ValidateDenom(denomOut); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid denomOut"})
+		return
+	}
+
+	slippage := defaultQuoteSlippage
+	if slippageStr, ok := context.GetQuery("slippage"); ok {
+		slippage, err = strconv.ParseFloat(slippageStr, 64)
+		if err != nil || slippage < 0 || slippage > 0.5 {
+			context.JSON(http.StatusBadRequest, gin.H{"error": "slippage must be between 0 and 0.5"})
+			return
+		}
+	}
+
+	address := context.Query("address")
+	if address != "" && !osmosis.IsValidCosmosAddress(address) {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid address"})
+		return
+	}
+
+	conf := config.Conf
+	txClient, err := osmosis.GetOsmosisTxClient(conf.Api.ChainID, conf.GetApiRpcSearchTxEndpoint(), conf.Api.KeyringHomeDir, conf.Api.KeyringBackend, conf.Api.HotWalletKey)
+	if err != nil {
+		config.Logger.Error("GetOsmosisTxClient", zap.Error(err))
+		context.JSON(http.StatusInternalServerError, "server misconfiguration (query client error), please notify administrator")
+		return
+	}
+
+	result, err := osmosis.Quote(txClient, tokenIn, denomOut, slippage, address)
+	if errors.Is(err, osmosis.ErrPoolDataUnavailable) {
+		config.Logger.Error("Quote", zap.Error(err))
+		context.JSON(http.StatusInternalServerError, "Internal RPC query failed, retry later")
+		return
+	} else if errors.Is(err, simulator.ErrNoRoute) {
+		context.JSON(http.StatusNotFound, gin.H{"error": "no route found from " + tokenIn.Denom + " to " + denomOut})
+		return
+	} else if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	context.JSON(http.StatusOK, result)
+}
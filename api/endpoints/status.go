@@ -1,15 +1,19 @@
 package endpoints
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/DefiantLabs/RedpointSwap/api"
 	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/DefiantLabs/RedpointSwap/osmosis"
 	"github.com/DefiantLabs/RedpointSwap/simulator"
 	"github.com/DefiantLabs/RedpointSwap/zenith"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/gin-gonic/gin"
+	gamm "github.com/osmosis-labs/osmosis/v13/x/gamm/types"
 )
 
 type AuthzTradeStatus struct {
@@ -19,28 +23,54 @@ type AuthzTradeStatus struct {
 	UserSwaps        []api.Swap //The user's swaps (for their 'normal' trade)
 	ErrorCheckStatus string     //if some error occurred checking the status (just query the status endpoint again)
 	TxError          string     //if some error occurred processing the user's TXs
+	FailedTxs        []FailedTx //Details for each of the trade TXs that failed on chain
+	Simulation       simulator.SimulatedSwapResult
 }
 
 type ZenithTradeStatus struct {
-	WaitingForBlock  bool  //True if we are waiting for an available zenith block
-	ZenithBlockBid   int64 //Will be non-zero if we bid on an auction block
-	ChainHeight      int64 //The last known height of the chain
-	TxsCommitted     bool  //True if our TXs were included in the block (only makes sense if ChainHeight >= ZenithBlockBid)
+	WaitingForBlock  bool   //True if we are waiting for an available zenith block
+	ZenithBlockBid   int64  //Will be non-zero if we bid on an auction block
+	ChainHeight      int64  //The last known height of the chain
+	TxsCommitted     bool   //True if our TXs were included in the block (only makes sense if ChainHeight >= ZenithBlockBid)
+	BidKind          string //Whether the request is bid for the top of the block or the whole block
+	BidStrategy      string //The strategy that priced our bid (or will price it, if we haven't bid yet)
+	BidOutcome       string //Outcome of our last bid: pending, won, lost or included (on chain without winning the auction)
+	BundleSize       int    //Number of user requests in our bid, including this one (their TXs are bid on together)
 	UserArbitrage    UserArbitrageEarnings
 	UserSwaps        []api.Swap //The user's swaps (for their 'normal' trade)
 	ErrorCheckStatus string     //if some error occurred checking the status (just query the status endpoint again)
 	TxError          string     //if some error occurred processing the user's TXs
+	FailedTxs        []FailedTx //Details for each of the trade TXs that failed on chain
 	Simulation       simulator.SimulatedSwapResult
 }
 
 type UserArbitrageEarnings struct {
-	ZenithArbitrageTxHash string     //The Zenith TX that captures arbitrage for the hot wallet
-	SendUserFundsTxHash   string     //The hash of the TX that sends the user their arbitrage earnings
-	HasArbitrage          bool       //Whether or not the user is owed any arbitrage
-	EstimatedEarnings     []sdk.Coin //What we think the user will receive, based on the simulation and fees the hot wallet paid
-	AmountInProgress      []sdk.Coin //Arb we owe to the user, we are working on sending (e.g. we submitted a TX to the chain)
-	AmountReceived        []sdk.Coin //If the user received the arbitrage (e.g. the TX we submitted succeeded)
-	Error                 string     //If there was some issue sending the user tokens or looking up the status
+	ZenithArbitrageTxHash string                //The Zenith TX that captures arbitrage for the hot wallet
+	SendUserFundsTxHash   string                //The hash of the TX that sends the user their arbitrage earnings
+	HasArbitrage          bool                  //Whether or not the user is owed any arbitrage
+	EstimatedEarnings     []sdk.Coin            //What we think the user will receive, based on the simulation and fees the hot wallet paid
+	AmountInProgress      []sdk.Coin            //Arb we owe to the user, we are working on sending (e.g. we submitted a TX to the chain)
+	AmountReceived        []sdk.Coin            //If the user received the arbitrage (e.g. the TX we submitted succeeded)
+	Error                 string                //If there was some issue sending the user tokens or looking up the status
+	ArbitrageSwaps        []ArbitrageSwapStatus //Each arbitrage swap in the simulation, in the order they execute
+	ArbitrageRevenue      []sdk.Coin            //Actual revenue of each arbitrage swap the hot wallet executed (once committed)
+}
+
+type FailedTx struct {
+	TxHash       string
+	ErrorCode    string //Stable error code, e.g. SLIPPAGE_EXCEEDED or INSUFFICIENT_FUNDS (see osmosis.TxError constants)
+	Code         uint32 //ABCI code
+	Codespace    string
+	MessageIndex int    //Index of the message that failed, -1 if unknown
+	Log          string //The chain's error message
+}
+
+type ArbitrageSwapStatus struct {
+	Pools            string                   //Comma separated list of pools the arbitrage swap trades through
+	Routes           gamm.SwapAmountInRoutes  `json:"routes,omitempty"`    //The simulated routes (MsgSwapExactAmountIn)
+	OutRoutes        gamm.SwapAmountOutRoutes `json:"outRoutes,omitempty"` //The simulated routes (MsgSwapExactAmountOut)
+	EstimatedRevenue sdk.Coin                 //Based on the simulation (does not include fees)
+	Hops             []osmosis.SwapHop        //What each pool actually returned (once committed)
 }
 
 func GetTradeStatus(context *gin.Context) {
@@ -83,30 +113,48 @@ func convertToZenithStatus(userTrade *api.ZenithArbitrageTxSet) ZenithTradeStatu
 		ts.UserArbitrage.ZenithArbitrageTxHash = userTrade.TradeTxs[1].TxHash
 	}
 
-	estimatedAmountOut := userTrade.Simulation.ArbitrageSwap.SimulatedSwap.TokenOutAmount.ToDec()
-	estimatedArbRevenue := estimatedAmountOut.Sub(userTrade.Simulation.ArbitrageSwap.SimulatedSwap.TokenIn.Amount.ToDec())
-	totalArbFees, err := zenith.EstimateArbFees(*userTrade.Simulation)
+	conf := config.Conf
+	txClientSearch, err := osmosis.GetOsmosisTxClient(conf.Api.ChainID, conf.GetApiRpcSearchTxEndpoint(), conf.Api.KeyringHomeDir, conf.Api.KeyringBackend, conf.Api.HotWalletKey)
+	if err != nil {
+		ts.UserArbitrage.Error = "Problem estimating arbitrage earnings, check back for on-chain results"
+		return ts
+	}
 
-	if err == nil {
-		conf := config.Conf
-		userProfitShare := 0.85
-		if conf.Api.UserProfitSharePercentage <= .85 {
-			userProfitShare = conf.Api.UserProfitSharePercentage
+	estimates, err := zenith.EstimateArbFees(*userTrade.Simulation, txClientSearch, userTrade.UserBidRequest.TimeLeft(time.Now()), userTrade.UserBidRequest.BidKind())
+	if err != nil {
+		ts.UserArbitrage.Error = "Problem estimating arbitrage earnings, check back for on-chain results"
+	}
+
+	userProfitShare := 0.85
+	if conf.Api.UserProfitSharePercentage <= .85 {
+		userProfitShare = conf.Api.UserProfitSharePercentage
+	}
+	userProfitShareStr := strconv.FormatFloat(userProfitShare, 'f', 6, 64)
+	userProfitShareDec, _ := sdk.NewDecFromStr(userProfitShareStr)
+
+	estimatedEarnings := sdk.NewCoins()
+	for _, estimate := range estimates {
+		if !estimate.Profitable {
+			continue
 		}
-		userProfitShareStr := strconv.FormatFloat(userProfitShare, 'f', 6, 64)
-		userProfitShareDec, err := sdk.NewDecFromStr(userProfitShareStr)
-
-		if err == nil {
-			expectedProfit := estimatedArbRevenue.TruncateInt().Sub(totalArbFees)
-			if expectedProfit.IsPositive() {
-				expectedProfit = expectedProfit.ToDec().Mul(userProfitShareDec).TruncateInt()
-				ts.UserArbitrage.EstimatedEarnings = sdk.NewCoins(sdk.NewCoin("uosmo", expectedProfit))
-			} else {
-				ts.UserArbitrage.HasArbitrage = false
-			}
+
+		//Fees are estimated in the fee denom, the arbitrage earns the denom it started with
+		arbDenom := estimate.Swap.GetTokenIn().Denom
+		arbFees, err := osmosis.FromFeeDenom(txClientSearch, estimate.TotalFees, arbDenom)
+		if err != nil {
+			ts.UserArbitrage.Error = "Problem estimating arbitrage earnings, check back for on-chain results"
+			continue
 		}
-	} else {
-		ts.UserArbitrage.Error = "Problem estimating arbitrage earnings, check back for on-chain results"
+
+		estimatedArbRevenue := estimate.Swap.GetTokenOut().Amount.Sub(estimate.Swap.GetTokenIn().Amount)
+		expectedProfit := estimatedArbRevenue.Sub(arbFees)
+		if expectedProfit.IsPositive() {
+			expectedProfit = expectedProfit.ToDec().Mul(userProfitShareDec).TruncateInt()
+			estimatedEarnings = estimatedEarnings.Add(sdk.NewCoin(arbDenom, expectedProfit))
+		}
+	}
+	if !estimatedEarnings.IsZero() {
+		ts.UserArbitrage.EstimatedEarnings = estimatedEarnings
 	}
 
 	// TXs are awaiting submission to a Zenith auction if:
@@ -117,11 +165,32 @@ func convertToZenithStatus(userTrade *api.ZenithArbitrageTxSet) ZenithTradeStatu
 	if userTrade.SubmittedAuctionBid != nil {
 		ts.ZenithBlockBid = userTrade.SubmittedAuctionBid.Height
 	}
+	if bid, ok := zenith.GetPlacedBid(userTrade.BidId); ok {
+		ts.BidOutcome = bid.Outcome
+	}
+	ts.BidKind = userTrade.UserBidRequest.BidKind()
+	ts.BidStrategy = userTrade.BidStrategy
+	ts.BundleSize = userTrade.BundleSize
+	if ts.BidStrategy == "" {
+		if strategy, err := zenith.GetBidStrategy(); err == nil {
+			ts.BidStrategy = strategy.Name()
+		}
+	}
 	if userTrade.ErrorPlacingBid {
 		ts.TxError = "Error placing bid, will reattempt"
 	}
+	if userTrade.DroppedReason != "" {
+		ts.TxError = "Request dropped: " + userTrade.DroppedReason
+	}
+
+	ts.FailedTxs = getFailedTxs(userTrade.TradeTxs)
+	if ts.TxError == "" && len(ts.FailedTxs) > 0 {
+		ts.TxError = txFailedMessage(ts.FailedTxs[0])
+	}
 
 	ts.WaitingForBlock = awaitingZenithBlock
+	ts.UserArbitrage.ArbitrageSwaps = getArbitrageSwaps(userTrade.Simulation, userTrade.TradeTxs)
+	ts.UserArbitrage.ArbitrageRevenue = userTrade.ArbitrageRevenues
 	ts.ChainHeight = userTrade.LastChainHeight
 	ts.UserSwaps = getUserSwaps(userTrade.TradeTxs)
 	ts.TxsCommitted = userTrade.Committed
@@ -140,6 +209,9 @@ func convertToZenithStatus(userTrade *api.ZenithArbitrageTxSet) ZenithTradeStatu
 	if userTrade.UserProfitShareTx.Initiated && userTrade.UserProfitShareTx.Committed && !userTrade.UserProfitShareTx.Succeeded {
 		ts.UserArbitrage.AmountReceived = sdk.Coins{}
 		ts.UserArbitrage.Error = "Problem sending user arbitrage (will not reattempt, please report address and time of trade)"
+		if failure := userTrade.UserProfitShareTx.Failure; failure != nil {
+			ts.UserArbitrage.Error += ". Error code: " + failure.ErrorCode
+		}
 	}
 
 	return ts
@@ -153,6 +225,15 @@ func convertToAuthzStatus(userTrade *api.AuthzArbitrageTxSet) AuthzTradeStatus {
 	ts.ChainHeight = userTrade.LastChainHeight
 	ts.UserSwaps = getUserSwaps(userTrade.TradeTxs)
 	ts.TxsCommitted = userTrade.Committed
+	ts.FailedTxs = getFailedTxs(userTrade.TradeTxs)
+	if len(ts.FailedTxs) > 0 {
+		ts.TxError = txFailedMessage(ts.FailedTxs[0])
+	}
+	if userTrade.Simulation != nil {
+		ts.Simulation = *userTrade.Simulation
+	}
+	ts.UserArbitrage.ArbitrageSwaps = getArbitrageSwaps(userTrade.Simulation, userTrade.TradeTxs)
+	ts.UserArbitrage.ArbitrageRevenue = userTrade.ArbitrageRevenues
 
 	if !userTrade.UserProfitShareTx.ArbitrageProfitsPending.IsZero() || !userTrade.UserProfitShareTx.ArbitrageProfitsReceived.IsZero() {
 		ts.UserArbitrage.HasArbitrage = true
@@ -167,11 +248,84 @@ func convertToAuthzStatus(userTrade *api.AuthzArbitrageTxSet) AuthzTradeStatus {
 	if userTrade.UserProfitShareTx.Initiated && userTrade.UserProfitShareTx.Committed && !userTrade.UserProfitShareTx.Succeeded {
 		ts.UserArbitrage.AmountReceived = sdk.Coins{}
 		ts.UserArbitrage.Error = "Problem sending user arbitrage (will not reattempt, please report address and time of trade)"
+		if failure := userTrade.UserProfitShareTx.Failure; failure != nil {
+			ts.UserArbitrage.Error += ". Error code: " + failure.ErrorCode
+		}
 	}
 
 	return ts
 }
 
+// Each simulated arbitrage swap, with the hops of the hot wallet swap that executed it (matched by the pools traded through)
+func getArbitrageSwaps(simulation *simulator.SimulatedSwapResult, tradeTxs []api.SubmittedTx) []ArbitrageSwapStatus {
+	executed := []api.Swap{}
+	for _, t := range tradeTxs {
+		for _, swap := range t.Swaps {
+			if swap.IsArbitrageSwap && swap.IsHotWalletSwap {
+				executed = append(executed, swap)
+			}
+		}
+	}
+
+	arbSwaps := []ArbitrageSwapStatus{}
+	for _, arbSwap := range simulation.GetArbitrageSwaps() {
+		swap := arbSwap.SimulatedSwap
+		revenue := sdk.Coin{Denom: swap.GetTokenIn().Denom, Amount: sdk.ZeroInt()}
+		if swap.GetTokenOut().Amount.GT(swap.GetTokenIn().Amount) {
+			revenue.Amount = swap.GetTokenOut().Amount.Sub(swap.GetTokenIn().Amount)
+		}
+
+		status := ArbitrageSwapStatus{Pools: swap.Pools, Routes: swap.Routes, OutRoutes: swap.OutRoutes, EstimatedRevenue: revenue}
+		for i, executedSwap := range executed {
+			if samePools(swap.PoolIds(), executedSwap.Hops) {
+				status.Hops = executedSwap.Hops
+				executed = append(executed[:i], executed[i+1:]...)
+				break
+			}
+		}
+		arbSwaps = append(arbSwaps, status)
+	}
+
+	return arbSwaps
+}
+
+func samePools(poolIds []uint64, hops []osmosis.SwapHop) bool {
+	if len(poolIds) != len(hops) {
+		return false
+	}
+
+	for i, hop := range hops {
+		if hop.PoolId != poolIds[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func getFailedTxs(tradeTxs []api.SubmittedTx) []FailedTx {
+	failedTxs := []FailedTx{}
+	for _, t := range tradeTxs {
+		if t.Failure == nil {
+			continue
+		}
+
+		failedTxs = append(failedTxs, FailedTx{
+			TxHash:       t.TxHash,
+			ErrorCode:    t.Failure.ErrorCode,
+			Code:         t.Failure.Code,
+			Codespace:    t.Failure.Codespace,
+			MessageIndex: t.Failure.MessageIndex,
+			Log:          t.Failure.Log,
+		})
+	}
+
+	return failedTxs
+}
+
+func txFailedMessage(failedTx FailedTx) string {
+	return fmt.Sprintf("TX %s failed (%s)", failedTx.TxHash, failedTx.ErrorCode)
+}
+
 func getUserSwaps(tradeTxs []api.SubmittedTx) []api.Swap {
 	swaps := []api.Swap{}
 	for _, t := range tradeTxs {
@@ -1,12 +1,14 @@
 package endpoints
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/DefiantLabs/RedpointSwap/api"
 	"github.com/DefiantLabs/RedpointSwap/config"
 	"github.com/DefiantLabs/RedpointSwap/osmosis"
+	"github.com/DefiantLabs/RedpointSwap/simulator"
 	"github.com/DefiantLabs/RedpointSwap/zenith"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -31,6 +33,16 @@ func QueueZenith(context *gin.Context) {
 		return
 	}
 
+	//Requests are bid for the top of the block unless they opt into a block bid
+	if !zenith.IsValidBidKind(req.Kind) {
+		context.JSON(http.StatusBadRequest, "kind must be top or block")
+		return
+	} else if req.Kind == zenith.BidKindBlock && !config.Conf.Zenith.BlockBidsEnabled {
+		context.JSON(http.StatusBadRequest, "block bids are not enabled on this server")
+		return
+	}
+	req.Kind = req.BidKind()
+
 	//Verify the cosmos address in the simulation
 	if !osmosis.IsValidCosmosAddress(req.SimulatedSwap.UserAddress) {
 		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid simulation provided"})
@@ -55,16 +67,47 @@ func QueueZenith(context *gin.Context) {
 	}
 
 	// Make sure the user's wallet has the requisite funds to do the swap
-	balanceOk := osmosis.HasTokens(req.SimulatedSwap.SimulatedUserSwap.TokenIn, userBalances)
+	balanceOk := osmosis.HasTokens(req.SimulatedSwap.SimulatedUserSwap.GetTokenIn(), userBalances)
 	if !balanceOk {
 		config.Logger.Info("Insufficient balance",
 			zap.String("user address", req.SimulatedSwap.UserAddress),
-			zap.String("token in", req.SimulatedSwap.SimulatedUserSwap.TokenIn.String()),
+			zap.String("token in", req.SimulatedSwap.SimulatedUserSwap.GetTokenIn().String()),
 		)
 		context.JSON(http.StatusBadRequest, "Insufficient balance")
 		return
 	}
 
+	//Never trust the client's simulated amounts (or the height it claims they were simulated at), recompute them from the current pool state
+	req.SimulatedSwap.SimulationHeight = 0
+	err = osmosis.VerifySimulation(txClient, &req.SimulatedSwap)
+	if errors.Is(err, osmosis.ErrPoolDataUnavailable) {
+		config.Logger.Error("VerifySimulation", zap.Error(err))
+		context.JSON(http.StatusInternalServerError, "Internal RPC query failed, retry later")
+		return
+	} else if err != nil {
+		config.Logger.Info("Simulation rejected", zap.String("user address", req.SimulatedSwap.UserAddress), zap.Error(err))
+		context.JSON(http.StatusBadRequest, "simulation does not match current pool state, simulate again")
+		return
+	}
+
+	//Only trade through pools and denoms the route policy allows
+	err = osmosis.CheckPolicy(txClient, &req.SimulatedSwap)
+	if errors.Is(err, simulator.ErrPolicyViolation) {
+		config.Logger.Info("Simulation rejected by policy", zap.String("user address", req.SimulatedSwap.UserAddress), zap.Error(err))
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		config.Logger.Error("CheckPolicy", zap.Error(err))
+		context.JSON(http.StatusInternalServerError, "could not check route policy, retry later")
+		return
+	}
+
+	//Our own route search may find arbitrage the client didn't (or a better one)
+	err = osmosis.FindArbitrage(txClient, &req.SimulatedSwap)
+	if err != nil {
+		config.Logger.Warn("FindArbitrage", zap.Error(err))
+	}
+
 	reqId := api.QueueZenithRequest(req)
 	context.JSON(http.StatusOK, gin.H{"status": "Queued Zenith request", "id": reqId})
 }
@@ -92,3 +135,8 @@ func ZenithAvailableBlocks(context *gin.Context) {
 		context.JSON(http.StatusOK, zBlocks)
 	}
 }
+
+// Win rate, average bid and profit after the bid for each day we placed Zenith bids, most recent day first
+func ZenithBidStats(context *gin.Context) {
+	context.JSON(http.StatusOK, zenith.GetBidStats())
+}
@@ -0,0 +1,305 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/DefiantLabs/RedpointSwap/osmosis"
+	"github.com/DefiantLabs/RedpointSwap/simulator"
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"go.uber.org/zap"
+)
+
+// Pending swaps are forgotten this many blocks after we first saw them
+const seenPendingSwapBlocks = 10
+
+// Pending swaps we already looked at. Key: TX hash/message index, Value: chain height when first seen
+var seenPendingSwaps sync.Map
+
+// The hot wallet keeps a back-run's capital for this many blocks after submitting it (by then it is committed or dropped from the mempool)
+const backrunPendingBlocks = 5
+
+// Back-runs are forgotten this many blocks after they were submitted (about a day)
+const backrunHistoryBlocks = 14400
+
+// Tracks every arbitrage TX submitted for a pending swap. Key: TX hash, Value: *MempoolBackrun.
+var mempoolBackruns sync.Map
+
+type MempoolBackrun struct {
+	TxHash           string
+	PendingTxHash    string //The pending swap the arbitrage trades after
+	Height           int64  //Chain height when the arbitrage was submitted
+	Time             time.Time
+	HotWalletAddress string
+	ArbitrageSwaps   []*simulator.ArbitrageSwap
+	EstimatedProfit  sdk.Int //In the fee denom, after gas
+	ErrorSubmit      string
+}
+
+// The arbitrage TX was accepted into the mempool and may not have executed yet
+func (backrun *MempoolBackrun) pending(height int64) bool {
+	return backrun.ErrorSubmit == "" && height <= backrun.Height+backrunPendingBlocks
+}
+
+// Every back-run we submitted, most recent first
+func GetBackruns() []MempoolBackrun {
+	backrunList := []MempoolBackrun{}
+	mempoolBackruns.Range(func(_, val any) bool {
+		backrunList = append(backrunList, *val.(*MempoolBackrun))
+		return true
+	})
+
+	sort.Slice(backrunList, func(i, j int) bool {
+		return backrunList[i].Time.After(backrunList[j].Time)
+	})
+	return backrunList
+}
+
+// Polls the mempools of our RPC nodes (see config RpcSubmitTxEndpoints) for pending swaps and back-runs them
+// with the hot wallet's arbitrage when it is profitable enough. Returns immediately if the mempool monitor is disabled.
+func MonitorMempool() {
+	conf := config.Conf
+	if !conf.Mempool.Enabled {
+		return
+	}
+
+	minProfit, err := sdk.ParseCoinNormalized(conf.Mempool.MinProfit)
+	if err != nil || minProfit.Denom != osmosis.FeeDenom {
+		config.Logger.Error("server misconfiguration (mempool MinProfit), mempool monitor disabled", zap.String("min profit", conf.Mempool.MinProfit))
+		return
+	}
+
+	nodeClients := []client.Context{}
+	for _, node := range strings.Split(conf.Api.RpcSubmitTxEndpoints, ",") {
+		nodeClient, err := osmosis.GetOsmosisTxClient(conf.Api.ChainID, strings.TrimSpace(node), conf.Api.KeyringHomeDir, conf.Api.KeyringBackend, conf.Api.HotWalletKey)
+		if err != nil {
+			config.Logger.Error("GetOsmosisTxClient", zap.Error(err), zap.String("node", node))
+			continue
+		}
+		nodeClients = append(nodeClients, nodeClient)
+	}
+
+	if len(nodeClients) == 0 {
+		config.Logger.Error("Mempool monitor has no RPC nodes to poll")
+		return
+	}
+
+	config.Logger.Info("Mempool monitor started", zap.Int("nodes", len(nodeClients)), zap.Duration("poll interval", conf.GetMempoolPollInterval()))
+	ticker := time.NewTicker(conf.GetMempoolPollInterval())
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if mempoolKillSwitchOn() || zenithBidOutstanding(osmosis.LatestHeight()) {
+			continue
+		}
+		pollMempool(nodeClients, minProfit.Amount)
+	}
+}
+
+// The kill switch is on while the configured KillSwitchFile exists
+func mempoolKillSwitchOn() bool {
+	killSwitchFile := config.Conf.Mempool.KillSwitchFile
+	if killSwitchFile == "" {
+		return false
+	}
+
+	_, err := os.Stat(killSwitchFile)
+	return err == nil
+}
+
+// Zenith bundles are signed ahead of the auction with the hot wallet's next sequences, so another hot wallet TX
+// committed first would invalidate the bundle. True while any Zenith bid's auction height hasn't passed.
+func zenithBidOutstanding(height int64) bool {
+	outstanding := false
+	txqueue.Range(func(_, val any) bool {
+		txSet, ok := val.(*ZenithArbitrageTxSet)
+		if ok && txSet.SubmittedAuctionBid != nil && !txSet.Committed && height <= txSet.SubmittedAuctionBid.Height {
+			outstanding = true
+		}
+		return !outstanding
+	})
+	return outstanding
+}
+
+// The pending swap moves prices enough to back-run, and it meets its minimum amount out (otherwise it fails on chain)
+func worthBackrunning(userSwap *simulator.SimulatedSwap, minPriceImpact float64) bool {
+	if userSwap.PriceImpact < minPriceImpact {
+		return false
+	}
+	return userSwap.TokenOutMinAmount.IsNil() || !userSwap.TokenOutAmount.LT(userSwap.TokenOutMinAmount)
+}
+
+// The arbitrage swaps that each earn at least minProfit (in the fee denom, after gas), with their total gas and profit
+func profitableBackrunSwaps(
+	arbSwaps []*simulator.ArbitrageSwap,
+	minProfit sdk.Int,
+	estimateProfit func(*simulator.SimulatedSwap) (uint64, sdk.Int, error),
+) ([]*simulator.ArbitrageSwap, uint64, sdk.Int) {
+	profitable := []*simulator.ArbitrageSwap{}
+	var gas uint64
+	estimatedProfit := sdk.ZeroInt()
+	for _, arbSwap := range arbSwaps {
+		arbGas, profit, err := estimateProfit(arbSwap.SimulatedSwap)
+		if err != nil || profit.LT(minProfit) {
+			continue
+		}
+
+		profitable = append(profitable, arbSwap)
+		gas += arbGas
+		estimatedProfit = estimatedProfit.Add(profit)
+	}
+
+	return profitable, gas, estimatedProfit
+}
+
+func pollMempool(nodeClients []client.Context, minProfit sdk.Int) {
+	height := osmosis.LatestHeight()
+	seenPendingSwaps.Range(func(key, val any) bool {
+		if height-val.(int64) > seenPendingSwapBlocks {
+			seenPendingSwaps.Delete(key)
+		}
+		return true
+	})
+	mempoolBackruns.Range(func(key, val any) bool {
+		if height-val.(*MempoolBackrun).Height > backrunHistoryBlocks {
+			mempoolBackruns.Delete(key)
+		}
+		return true
+	})
+
+	for _, nodeClient := range nodeClients {
+		pendingSwaps, err := osmosis.GetPendingSwaps(nodeClient)
+		if err != nil {
+			config.Logger.Warn("Mempool: failed to query unconfirmed TXs", zap.String("node", nodeClient.NodeURI), zap.Error(err))
+			continue
+		}
+
+		for _, pendingSwap := range pendingSwaps {
+			key := fmt.Sprintf("%s/%d", pendingSwap.TxHash, pendingSwap.MsgIndex)
+			if _, seen := seenPendingSwaps.LoadOrStore(key, height); seen {
+				continue
+			} else if pendingSwap.Sender == config.HotWalletAddress || pendingSwap.Sender == config.RetiringHotWalletAddress {
+				continue
+			}
+
+			//Our TXs are submitted through the first node
+			backrunPendingSwap(nodeClients[0], pendingSwap, height, minProfit)
+		}
+	}
+}
+
+// Submits the hot wallet's arbitrage for the pending swap, if the swap moves prices enough for arbitrage
+// that earns at least minProfit (in the fee denom, after gas).
+func backrunPendingSwap(txClient client.Context, pendingSwap osmosis.PendingSwap, height int64, minProfit sdk.Int) {
+	conf := config.Conf
+	userSwap := pendingSwap.Swap
+
+	pools, err := osmosis.GetPoolStates(txClient, userSwap.PoolIds())
+	if err != nil {
+		return
+	}
+
+	amountIn, err := userSwap.TokenIn.Amount.ToDec().Float64()
+	if err != nil {
+		return
+	}
+
+	amountOut, priceImpact, err := pools.PriceImpact(userSwap.Hops(), amountIn)
+	if err != nil {
+		return
+	}
+
+	userSwap.TokenOutAmount, err = simulator.IntFromFloat(amountOut)
+	if err != nil {
+		return
+	}
+	userSwap.PriceImpact = priceImpact
+	if !worthBackrunning(userSwap, conf.Mempool.MinPriceImpact) {
+		return
+	}
+
+	result := &simulator.SimulatedSwapResult{SimulatedUserSwap: userSwap, UserAddress: pendingSwap.Sender, SimulationHeight: height}
+	err = osmosis.FindArbitrage(txClient, result)
+	if err != nil || !result.HasArbitrageOpportunity {
+		return
+	}
+
+	arbSwaps, gas, estimatedProfit := profitableBackrunSwaps(result.GetArbitrageSwaps(), minProfit, func(arbSwap *simulator.SimulatedSwap) (uint64, sdk.Int, error) {
+		return estimateBackrunProfit(txClient, arbSwap)
+	})
+	if len(arbSwaps) == 0 {
+		return
+	}
+
+	//The kill switch may have been turned on, or a Zenith bid placed, while we were searching for arbitrage
+	if mempoolKillSwitchOn() || zenithBidOutstanding(osmosis.LatestHeight()) {
+		return
+	}
+
+	arbSimulatedSwaps := []*simulator.SimulatedSwap{}
+	for _, arbSwap := range arbSwaps {
+		arbSimulatedSwaps = append(arbSimulatedSwaps, arbSwap.SimulatedSwap)
+	}
+
+	//The arbitrage's minimum amount out is its amount in, so if it lands before the pending swap it fails (costing only gas)
+	msgs, err := osmosis.BuildArbitrages(txClient, userSwap, arbSimulatedSwaps)
+	if err != nil {
+		config.Logger.Info("Mempool: could not build arbitrage", zap.String("pending tx", pendingSwap.TxHash), zap.Error(err))
+		return
+	}
+
+	backrun := &MempoolBackrun{
+		PendingTxHash:    pendingSwap.TxHash,
+		Height:           height,
+		Time:             time.Now(),
+		HotWalletAddress: config.HotWalletAddress,
+		ArbitrageSwaps:   arbSwaps,
+		EstimatedProfit:  estimatedProfit,
+	}
+
+	resp, err := osmosis.SignSubmitTx(txClient, msgs, gas)
+	if err != nil || resp == nil {
+		config.Logger.Error("Audit: mempool arbitrage failed", zap.Error(err), zap.String("pending tx", pendingSwap.TxHash))
+		return
+	}
+
+	backrun.TxHash = resp.TxHash
+	if resp.Code != 0 {
+		backrun.ErrorSubmit = fmt.Sprintf("TX code %d: %s", resp.Code, resp.RawLog)
+	}
+	mempoolBackruns.Store(backrun.TxHash, backrun)
+
+	//Both the pending swap and our arbitrage change the pools
+	osmosis.InvalidatePools(result.PoolIds())
+	config.Logger.Info("Audit: mempool arbitrage submitted",
+		zap.String("tx hash", backrun.TxHash),
+		zap.String("pending tx", pendingSwap.TxHash),
+		zap.Int("arbitrage swaps", len(arbSwaps)),
+		zap.String("estimated profit", sdk.NewCoin(osmosis.FeeDenom, estimatedProfit).String()),
+		zap.String("error", backrun.ErrorSubmit),
+	)
+}
+
+// Gas for the arbitrage swap and its estimated profit in the fee denom after paying for the gas
+func estimateBackrunProfit(queryClient client.Context, arbSwap *simulator.SimulatedSwap) (uint64, sdk.Int, error) {
+	gas, err := osmosis.EstimateArbGas(arbSwap)
+	if err != nil {
+		return 0, sdk.Int{}, err
+	}
+
+	tokenIn := arbSwap.GetTokenIn()
+	revenue := arbSwap.GetTokenOut().Amount.Sub(tokenIn.Amount)
+	revenueFeeDenom, err := osmosis.ToFeeDenom(queryClient, revenue, tokenIn.Denom)
+	if err != nil {
+		return 0, sdk.Int{}, err
+	}
+
+	gasFee := sdk.NewIntFromUint64(gas).Quo(sdk.NewInt(200)) //equivalent of dividing by .005, which is the gasPrice amount
+	return gas, revenueFeeDenom.Sub(gasFee), nil
+}
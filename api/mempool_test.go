@@ -0,0 +1,166 @@
+package api
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/DefiantLabs/RedpointSwap/simulator"
+	"github.com/DefiantLabs/RedpointSwap/zenith"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func TestWorthBackrunning(t *testing.T) {
+	cases := []struct {
+		name           string
+		priceImpact    float64
+		tokenOut       int64
+		tokenOutMin    sdk.Int
+		minPriceImpact float64
+		worth          bool
+	}{
+		{"above the minimum price impact", 0.01, 1000, sdk.NewInt(900), 0.005, true},
+		{"at the minimum price impact", 0.005, 1000, sdk.NewInt(900), 0.005, true},
+		{"below the minimum price impact", 0.004, 1000, sdk.NewInt(900), 0.005, false},
+		{"no minimum price impact", 0, 1000, sdk.NewInt(900), 0, true},
+		{"meets the minimum amount out exactly", 0.01, 1000, sdk.NewInt(1000), 0.005, true},
+		{"fails its minimum amount out", 0.01, 1000, sdk.NewInt(1001), 0.005, false},
+		{"no minimum amount out", 0.01, 1000, sdk.Int{}, 0.005, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			userSwap := &simulator.SimulatedSwap{PriceImpact: c.priceImpact, TokenOutAmount: sdk.NewInt(c.tokenOut), TokenOutMinAmount: c.tokenOutMin}
+			if worth := worthBackrunning(userSwap, c.minPriceImpact); worth != c.worth {
+				t.Errorf("worth back-running is %t, expected %t", worth, c.worth)
+			}
+		})
+	}
+}
+
+func TestProfitableBackrunSwaps(t *testing.T) {
+	//Each arbitrage swap's amount in is the profit (and gas) the estimate returns for it, negative amounts fail to estimate
+	estimate := func(arbSwap *simulator.SimulatedSwap) (uint64, sdk.Int, error) {
+		if arbSwap.TokenIn.Amount.IsNegative() {
+			return 0, sdk.Int{}, errors.New("no price")
+		}
+		return arbSwap.TokenIn.Amount.Uint64(), arbSwap.TokenIn.Amount, nil
+	}
+	arbSwap := func(profit int64) *simulator.ArbitrageSwap {
+		return &simulator.ArbitrageSwap{SimulatedSwap: &simulator.SimulatedSwap{TokenIn: sdk.Coin{Denom: "uosmo", Amount: sdk.NewInt(profit)}}}
+	}
+
+	cases := []struct {
+		name           string
+		profits        []int64
+		minProfit      int64
+		expectedCount  int
+		expectedGas    uint64
+		expectedProfit int64
+	}{
+		{"no arbitrage", nil, 100, 0, 0, 0},
+		{"every swap profitable", []int64{100, 250}, 100, 2, 350, 350},
+		{"one swap below the minimum", []int64{99, 250}, 100, 1, 250, 250},
+		{"every swap below the minimum", []int64{10, 20}, 100, 0, 0, 0},
+		{"estimate fails", []int64{-1, 300}, 100, 1, 300, 300},
+		{"zero minimum profit", []int64{0, 5}, 0, 2, 5, 5},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			arbSwaps := []*simulator.ArbitrageSwap{}
+			for _, profit := range c.profits {
+				arbSwaps = append(arbSwaps, arbSwap(profit))
+			}
+
+			profitable, gas, profit := profitableBackrunSwaps(arbSwaps, sdk.NewInt(c.minProfit), estimate)
+			if len(profitable) != c.expectedCount || gas != c.expectedGas || !profit.Equal(sdk.NewInt(c.expectedProfit)) {
+				t.Errorf("got %d swaps, %d gas, %s profit, expected %d swaps, %d gas, %d profit", len(profitable), gas, profit, c.expectedCount, c.expectedGas, c.expectedProfit)
+			}
+		})
+	}
+}
+
+func TestMempoolKillSwitch(t *testing.T) {
+	confBefore := config.Conf
+	defer func() { config.Conf = confBefore }()
+
+	killSwitchFile := filepath.Join(t.TempDir(), "stop-mempool")
+
+	config.Conf.Mempool.KillSwitchFile = ""
+	if mempoolKillSwitchOn() {
+		t.Error("kill switch is on without a kill switch file configured")
+	}
+
+	config.Conf.Mempool.KillSwitchFile = killSwitchFile
+	if mempoolKillSwitchOn() {
+		t.Error("kill switch is on before the file exists")
+	}
+
+	if err := os.WriteFile(killSwitchFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if !mempoolKillSwitchOn() {
+		t.Error("kill switch is off while the file exists")
+	}
+
+	if err := os.Remove(killSwitchFile); err != nil {
+		t.Fatal(err)
+	}
+	if mempoolKillSwitchOn() {
+		t.Error("kill switch is still on after the file was removed")
+	}
+}
+
+func TestZenithBidOutstanding(t *testing.T) {
+	cases := []struct {
+		name        string
+		txSet       any
+		outstanding bool
+	}{
+		{"no requests", nil, false},
+		{"authz request", &AuthzArbitrageTxSet{}, false},
+		{"Zenith request without a bid", &ZenithArbitrageTxSet{}, false},
+		{"bid on a later block", &ZenithArbitrageTxSet{SubmittedAuctionBid: &zenith.ZenithBidRequest{Height: 102}}, true},
+		{"bid on the current block", &ZenithArbitrageTxSet{SubmittedAuctionBid: &zenith.ZenithBidRequest{Height: 100}}, true},
+		{"bid on a past block", &ZenithArbitrageTxSet{SubmittedAuctionBid: &zenith.ZenithBidRequest{Height: 99}}, false},
+		{"bid already committed", &ZenithArbitrageTxSet{SubmittedAuctionBid: &zenith.ZenithBidRequest{Height: 102}, SubmittedTxSet: SubmittedTxSet{Committed: true}}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.txSet != nil {
+				txqueue.Store("test", c.txSet)
+				defer txqueue.Delete("test")
+			}
+
+			if outstanding := zenithBidOutstanding(100); outstanding != c.outstanding {
+				t.Errorf("bid outstanding is %t, expected %t", outstanding, c.outstanding)
+			}
+		})
+	}
+}
+
+func TestMempoolBackrunPending(t *testing.T) {
+	cases := []struct {
+		name    string
+		backrun MempoolBackrun
+		height  int64
+		pending bool
+	}{
+		{"just submitted", MempoolBackrun{Height: 100}, 100, true},
+		{"last pending block", MempoolBackrun{Height: 100}, 100 + backrunPendingBlocks, true},
+		{"committed or dropped", MempoolBackrun{Height: 100}, 101 + backrunPendingBlocks, false},
+		{"rejected by the node", MempoolBackrun{Height: 100, ErrorSubmit: "TX code 32"}, 100, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if pending := c.backrun.pending(c.height); pending != c.pending {
+				t.Errorf("pending is %t, expected %t", pending, c.pending)
+			}
+		})
+	}
+}
@@ -58,8 +58,12 @@ func initRouter(allowedCORSDomains string) *gin.Engine {
 
 	api.GET("/status", endpoints.GetTradeStatus)                 //get status of a given in progress or completed trade
 	api.GET("/zenithavailable", endpoints.ZenithAvailableBlocks) //get list of available zenith blocks
+	api.GET("/zenithstats", endpoints.ZenithBidStats)            //daily stats for our zenith bids (win rate, average bid, profit after the bid)
 	api.GET("/grantee", endpoints.AuthzGranteeInfo)              //API endpoint so that clients know what hot wallet to authorize for grants
+	api.GET("/sweeps", endpoints.GetSweeps)                      //sweeps from the hot wallet to the cold wallet
+	api.GET("/backruns", endpoints.GetBackruns)                  //arbitrage TXs submitted after pending swaps in the mempool
 	api.POST("/token", endpoints.GenerateToken)
+	api.GET("/quote", endpoints.GetQuote) //best route (and any arbitrage) for a user swap, as a simulation ready to submit
 
 	//TODO: Consider if this should be under secured route. Bid fees are a concern.
 	api.POST("/zenith", endpoints.QueueZenith)
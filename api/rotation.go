@@ -0,0 +1,204 @@
+package api
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/DefiantLabs/RedpointSwap/osmosis"
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"go.uber.org/zap"
+)
+
+// Gas for the MsgSend that moves the retiring hot wallet's funds to the new hot wallet
+const rotationTxGas = 100000
+
+// Tracks the hot wallet key rotation (see config RetiringHotWalletKey).
+// The retiring key stays in use until every authz grant naming it has expired and its trades and payouts are finished.
+// rotationLock only guards the state below, it is never held across RPC calls.
+var rotationLock sync.Mutex
+var rotationStarted bool
+var retiringGrantsExpiration time.Time
+var rotationMigrationTxHash string
+var rotationComplete bool
+
+// Only one block handler moves the retiring hot wallet's funds at a time
+var rotationHandlerLock sync.Mutex
+
+// Grants naming the retiring address may have been issued before the app started,
+// so assume they can be valid for up to MaximumAuthzGrantSeconds. No new grants naming it are accepted from now on,
+// otherwise each one would push the rotation back.
+func StartKeyRotation(start time.Time) {
+	rotationLock.Lock()
+	defer rotationLock.Unlock()
+	rotationStarted = true
+	retiringGrantsExpiration = start.Add(time.Duration(config.Conf.Authz.MaximumAuthzGrantSeconds) * time.Second)
+}
+
+// Grants naming the retiring hot wallet are still honored (but no new ones are accepted) until they have all expired
+func RetiringGranteeDraining() bool {
+	if config.RetiringHotWalletAddress == "" {
+		return false
+	}
+
+	rotationLock.Lock()
+	defer rotationLock.Unlock()
+	return rotationStarted && time.Now().Before(retiringGrantsExpiration)
+}
+
+// Whether the TX set still needs the hot wallet that submitted it (e.g. to send the user their profit share)
+func (txSet *SubmittedTxSet) isFinished() bool {
+	if !txSet.Committed || !txSet.UserProfitShareTx.Initiated {
+		return false
+	}
+
+	return txSet.UserProfitShareTx.TxHash == "" || txSet.UserProfitShareTx.Committed
+}
+
+// True if any trade or payout in progress depends on the given hot wallet address
+func hasPendingTxSets(hotWalletAddress string) bool {
+	pending := false
+	txqueue.Range(func(_, val any) bool {
+		switch txSet := val.(type) {
+		case *AuthzArbitrageTxSet:
+			pending = txSet.HotWalletAddress == hotWalletAddress && !txSet.isFinished()
+		case *ZenithArbitrageTxSet:
+			pending = txSet.HotWalletAddress == hotWalletAddress && !txSet.isFinished() && txSet.DroppedReason == ""
+		}
+		return !pending
+	})
+
+	return pending
+}
+
+// This function is called for every new block produced on the chain.
+// Once nothing depends on the retiring hot wallet anymore, its funds are sent to the new hot wallet.
+func RotationBlockNotificationHandler(chainHeight int64, _ int64) {
+	conf := config.Conf
+	if config.RetiringHotWalletAddress == "" {
+		return
+	}
+
+	if !rotationHandlerLock.TryLock() {
+		return
+	}
+	defer rotationHandlerLock.Unlock()
+
+	rotationLock.Lock()
+	complete, migrationTxHash, grantsExpiration := rotationComplete, rotationMigrationTxHash, retiringGrantsExpiration
+	rotationLock.Unlock()
+	if complete {
+		return
+	}
+
+	txClientSearch, err := osmosis.GetOsmosisTxClient(conf.Api.ChainID, conf.GetApiRpcSearchTxEndpoint(), conf.Api.KeyringHomeDir, conf.Api.KeyringBackend, conf.Api.RetiringHotWalletKey)
+	if err != nil {
+		config.Logger.Error("GetOsmosisTxClient", zap.Error(err))
+		return
+	}
+
+	//See if the funds were moved to the new hot wallet
+	if migrationTxHash != "" {
+		resp, err := osmosis.AwaitTx(txClientSearch, migrationTxHash, 500*time.Millisecond)
+		if err != nil {
+			fmt.Printf("Error %s looking up key rotation TX with hash %s\n", err.Error(), migrationTxHash)
+			return
+		}
+
+		if resp.TxResponse.Code == 0 {
+			setRotationState(true, migrationTxHash)
+			refreshArbBalances(txClientSearch, config.HotWalletAddress)
+			refreshArbBalances(txClientSearch, config.RetiringHotWalletAddress)
+			config.Logger.Info("Audit: hot wallet key rotation complete",
+				zap.String("tx hash", migrationTxHash),
+				zap.String("retiring address", config.RetiringHotWalletAddress),
+				zap.String("new address", config.HotWalletAddress),
+			)
+			return
+		}
+
+		//Try again on the next block
+		config.Logger.Error("Audit: hot wallet key rotation TX failed", zap.String("tx hash", migrationTxHash), zap.Uint32("TX code", resp.TxResponse.Code))
+		setRotationState(false, "")
+		return
+	}
+
+	if time.Now().Before(grantsExpiration) {
+		return
+	} else if hasPendingTxSets(config.RetiringHotWalletAddress) {
+		fmt.Printf("Key rotation: waiting for trades and payouts from %s to finish\n", config.RetiringHotWalletAddress)
+		return
+	}
+
+	balances, err := osmosis.GetAccountBalances(txClientSearch, config.RetiringHotWalletAddress)
+	if err != nil {
+		config.Logger.Error("Key rotation: failed to look up retiring hot wallet balances", zap.Error(err))
+		return
+	}
+
+	//Leave enough in the retiring wallet to pay for the migration TX
+	gasFee := sdk.NewCoin("uosmo", sdk.NewInt(rotationTxGas).QuoRaw(200)) //equivalent of multiplying by .005, which is the gasPrice amount
+	migrate := sdk.Coins{}
+	for denom, amount := range balances {
+		if denom == gasFee.Denom {
+			amount = amount.Sub(gasFee.Amount)
+		}
+		if amount.IsPositive() {
+			migrate = migrate.Add(sdk.NewCoin(denom, amount))
+		}
+	}
+
+	if migrate.IsZero() {
+		setRotationState(true, "")
+		config.Logger.Info("Audit: hot wallet key rotation complete (retiring wallet empty)", zap.String("retiring address", config.RetiringHotWalletAddress))
+		return
+	}
+
+	txClientSubmit, err := osmosis.GetOsmosisTxClient(conf.Api.ChainID, conf.GetApiRpcSubmitTxEndpoint(), conf.Api.KeyringHomeDir, conf.Api.KeyringBackend, conf.Api.RetiringHotWalletKey)
+	if err != nil {
+		config.Logger.Error("GetOsmosisTxClient", zap.Error(err))
+		return
+	}
+
+	msgSend := &bank.MsgSend{
+		FromAddress: config.RetiringHotWalletAddress,
+		ToAddress:   config.HotWalletAddress,
+		Amount:      migrate,
+	}
+
+	resp, err := osmosis.SignSubmitTx(txClientSubmit, []sdk.Msg{msgSend}, rotationTxGas)
+	if err != nil || resp == nil {
+		config.Logger.Error("Audit: hot wallet key rotation TX could not be submitted", zap.Error(err))
+		return
+	} else if resp.Code != 0 {
+		config.Logger.Error("Audit: hot wallet key rotation TX rejected", zap.Uint32("TX code", resp.Code), zap.String("tx hash", resp.TxHash))
+		return
+	}
+
+	setRotationState(false, resp.TxHash)
+	config.Logger.Info("Audit: hot wallet key rotation TX submitted",
+		zap.String("tx hash", resp.TxHash),
+		zap.Int64("height", chainHeight),
+		zap.String("amount", migrate.String()),
+		zap.String("retiring address", config.RetiringHotWalletAddress),
+		zap.String("new address", config.HotWalletAddress),
+	)
+}
+
+// Trades are sized against the arbitrage balances, so they must be refreshed whenever we move the hot wallet's funds
+func refreshArbBalances(queryClient client.Context, hotWalletAddress string) {
+	err := osmosis.RefreshHotWalletArbBalances(queryClient, hotWalletAddress)
+	if err != nil {
+		config.Logger.Error("Failed to refresh hot wallet arbitrage balances", zap.String("address", hotWalletAddress), zap.Error(err))
+	}
+}
+
+func setRotationState(complete bool, migrationTxHash string) {
+	rotationLock.Lock()
+	defer rotationLock.Unlock()
+	rotationComplete = complete
+	rotationMigrationTxHash = migrationTxHash
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/DefiantLabs/RedpointSwap/osmosis"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"go.uber.org/zap"
+)
+
+// Gas for a single MsgSend from the hot wallet
+const sweepTxGas = 100000
+
+// Sweep TXs can't be included more than this many blocks after they are submitted.
+// A sweep that isn't on chain by then is given up on, so sweeping can continue.
+const sweepTimeoutBlocks = 20
+
+// Tracks every sweep from the hot wallet to the cold wallet. Key: TX hash, Value: *SweepTx.
+var sweeps sync.Map
+var sweepLock sync.Mutex
+var lastSweepHeight int64
+var pendingSweep *SweepTx
+
+type SweepTx struct {
+	TxHash      string
+	Height      int64     //Chain height when the sweep was submitted
+	Expired     bool      //The sweep wasn't included before its timeout height
+	Time        time.Time //Time the sweep was submitted
+	From        string
+	To          string
+	Amount      sdk.Coins
+	Reserved    sdk.Coins //Amount left in the hot wallet for pending trades and user payouts (in addition to the working capital)
+	Committed   bool
+	Succeeded   bool
+	ErrorSubmit string
+}
+
+// Amount the hot wallet must keep for in progress trades and user payouts that haven't been sent yet.
+func pendingReservations(hotWalletAddress string) sdk.Coins {
+	reserved := sdk.Coins{}
+
+	reserveTxSet := func(txSet *SubmittedTxSet) {
+		if txSet.HotWalletAddress != "" && txSet.HotWalletAddress != hotWalletAddress {
+			return
+		}
+
+		if !txSet.Committed {
+			//The arbitrage swaps haven't executed yet, so keep enough to pay for them
+			if txSet.Simulation != nil {
+				for _, arbSwap := range txSet.Simulation.GetArbitrageSwaps() {
+					reserved = reserved.Add(arbSwap.SimulatedSwap.GetTokenIn())
+				}
+			}
+			reserved = reserved.Add(txSet.HotWalletTxFees...)
+		} else if !txSet.UserProfitShareTx.Initiated {
+			//We owe the user a share of the revenue but haven't sent it yet
+			reserved = reserved.Add(txSet.TotalArbitrageRevenue...)
+		} else if !txSet.UserProfitShareTx.Committed {
+			reserved = reserved.Add(txSet.UserProfitShareTx.ArbitrageProfitsPending...)
+		}
+	}
+
+	txqueue.Range(func(_, val any) bool {
+		switch txSet := val.(type) {
+		case *AuthzArbitrageTxSet:
+			reserveTxSet(&txSet.SubmittedTxSet)
+		case *ZenithArbitrageTxSet:
+			//Dropped and expired requests will never trade
+			if txSet.DroppedReason == "" && !txSet.IsExpired(time.Now()) {
+				reserveTxSet(&txSet.SubmittedTxSet)
+			}
+		}
+		return true
+	})
+
+	//Back-runs that may not have executed yet need their arbitrage capital
+	height := osmosis.LatestHeight()
+	mempoolBackruns.Range(func(_, val any) bool {
+		backrun := val.(*MempoolBackrun)
+		if backrun.HotWalletAddress == hotWalletAddress && backrun.pending(height) {
+			for _, arbSwap := range backrun.ArbitrageSwaps {
+				reserved = reserved.Add(arbSwap.SimulatedSwap.GetTokenIn())
+			}
+		}
+		return true
+	})
+
+	return reserved
+}
+
+// Amount the hot wallet holds above the working capital and pending reservations
+func sweepableBalance(balances map[string]sdk.Int, workingCapital sdk.Coins, reserved sdk.Coins) sdk.Coins {
+	sweepable := sdk.Coins{}
+	for denom, amount := range balances {
+		keep := workingCapital.AmountOf(denom).Add(reserved.AmountOf(denom))
+		if amount.GT(keep) {
+			sweepable = sweepable.Add(sdk.NewCoin(denom, amount.Sub(keep)))
+		}
+	}
+
+	return sweepable
+}
+
+// This function is called for every new block produced on the chain.
+// Hot wallet balances above the configured working capital are sent to the cold wallet,
+// either every SweepIntervalBlocks or as soon as SweepThreshold is reached.
+func SweepBlockNotificationHandler(chainHeight int64, _ int64) {
+	conf := config.Conf
+	if conf.Sweep.ColdWalletAddress == "" {
+		return
+	}
+
+	//Blocks may be processed concurrently, only one sweep can be in progress at a time
+	if !sweepLock.TryLock() {
+		return
+	}
+	defer sweepLock.Unlock()
+
+	if lastSweepHeight == 0 {
+		lastSweepHeight = chainHeight
+	}
+
+	txClientSearch, err := osmosis.GetOsmosisTxClient(conf.Api.ChainID, conf.GetApiRpcSearchTxEndpoint(), conf.Api.KeyringHomeDir, conf.Api.KeyringBackend, conf.Api.HotWalletKey)
+	if err != nil {
+		config.Logger.Error("GetOsmosisTxClient", zap.Error(err))
+		return
+	}
+
+	//Wait for the last sweep to finish before sending another one
+	if pendingSweep != nil {
+		resp, err := osmosis.AwaitTx(txClientSearch, pendingSweep.TxHash, 500*time.Millisecond)
+		if err != nil && chainHeight > pendingSweep.Height+sweepTimeoutBlocks+1 {
+			//Past the timeout height (with a block for the search node to catch up), the sweep can't be included anymore
+			pendingSweep.Expired = true
+			config.Logger.Error("Audit: hot wallet sweep expired",
+				zap.String("tx hash", pendingSweep.TxHash),
+				zap.Int64("height", pendingSweep.Height),
+				zap.String("amount", pendingSweep.Amount.String()),
+			)
+		} else if err != nil {
+			fmt.Printf("Error %s looking up sweep TX with hash %s\n", err.Error(), pendingSweep.TxHash)
+			return
+		} else {
+			pendingSweep.Committed = true
+			pendingSweep.Succeeded = resp.TxResponse.Code == 0
+			if pendingSweep.Succeeded {
+				refreshArbBalances(txClientSearch, config.HotWalletAddress)
+			}
+			config.Logger.Info("Audit: hot wallet sweep committed",
+				zap.String("tx hash", pendingSweep.TxHash),
+				zap.Bool("succeeded", pendingSweep.Succeeded),
+				zap.String("amount", pendingSweep.Amount.String()),
+				zap.String("to", pendingSweep.To),
+			)
+		}
+		pendingSweep = nil
+	}
+
+	workingCapital, err := sdk.ParseCoinsNormalized(conf.Sweep.WorkingCapital)
+	if err != nil {
+		config.Logger.Error("server misconfiguration (sweep WorkingCapital)", zap.Error(err))
+		return
+	}
+
+	sweepThreshold := sdk.Coins{}
+	if conf.Sweep.SweepThreshold != "" {
+		sweepThreshold, err = sdk.ParseCoinsNormalized(conf.Sweep.SweepThreshold)
+		if err != nil {
+			config.Logger.Error("server misconfiguration (sweep SweepThreshold)", zap.Error(err))
+			return
+		}
+	}
+
+	balances, err := osmosis.GetAccountBalances(txClientSearch, config.HotWalletAddress)
+	if err != nil {
+		config.Logger.Error("Sweep: failed to look up hot wallet balances", zap.Error(err))
+		return
+	}
+
+	reserved := pendingReservations(config.HotWalletAddress)
+	sweepable := sweepableBalance(balances, workingCapital, reserved)
+	if sweepable.IsZero() {
+		return
+	}
+
+	thresholdReached := !sweepThreshold.IsZero() && sweepable.IsAnyGTE(sweepThreshold)
+	intervalReached := conf.Sweep.SweepIntervalBlocks > 0 && chainHeight-lastSweepHeight >= conf.Sweep.SweepIntervalBlocks
+	if !thresholdReached && !intervalReached {
+		return
+	}
+
+	//A Zenith bundle signed for an upcoming block counts on the hot wallet's next sequence, so the sweep waits for the auction
+	if zenithBidOutstanding(chainHeight) {
+		return
+	}
+
+	txClientSubmit, err := osmosis.GetOsmosisTxClient(conf.Api.ChainID, conf.GetApiRpcSubmitTxEndpoint(), conf.Api.KeyringHomeDir, conf.Api.KeyringBackend, conf.Api.HotWalletKey)
+	if err != nil {
+		config.Logger.Error("GetOsmosisTxClient", zap.Error(err))
+		return
+	}
+
+	msgSend := &bank.MsgSend{
+		FromAddress: config.HotWalletAddress,
+		ToAddress:   conf.Sweep.ColdWalletAddress,
+		Amount:      sweepable,
+	}
+
+	sweep := &SweepTx{
+		Height:   chainHeight,
+		Time:     time.Now(),
+		From:     config.HotWalletAddress,
+		To:       conf.Sweep.ColdWalletAddress,
+		Amount:   sweepable,
+		Reserved: reserved,
+	}
+
+	lastSweepHeight = chainHeight
+	resp, err := osmosis.SignSubmitTxWithTimeout(txClientSubmit, []sdk.Msg{msgSend}, sweepTxGas, uint64(chainHeight+sweepTimeoutBlocks))
+	if err != nil || resp == nil {
+		config.Logger.Error("Audit: hot wallet sweep failed", zap.Error(err), zap.String("amount", sweepable.String()))
+		return
+	} else if resp.Code != 0 {
+		sweep.TxHash = resp.TxHash
+		sweep.Committed = true
+		sweep.ErrorSubmit = fmt.Sprintf("TX code %d: %s", resp.Code, resp.RawLog)
+		sweeps.Store(sweep.TxHash, sweep)
+		config.Logger.Error("Audit: hot wallet sweep rejected", zap.Uint32("TX code", resp.Code), zap.String("tx hash", resp.TxHash))
+		return
+	}
+
+	sweep.TxHash = resp.TxHash
+	sweeps.Store(sweep.TxHash, sweep)
+	pendingSweep = sweep
+
+	config.Logger.Info("Audit: hot wallet sweep submitted",
+		zap.String("tx hash", sweep.TxHash),
+		zap.Int64("height", chainHeight),
+		zap.String("amount", sweepable.String()),
+		zap.String("reserved", reserved.String()),
+		zap.String("working capital", workingCapital.String()),
+		zap.String("to", sweep.To),
+	)
+}
+
+// Every sweep we submitted, most recent first
+func GetSweeps() []SweepTx {
+	sweepList := []SweepTx{}
+	sweeps.Range(func(_, val any) bool {
+		sweepList = append(sweepList, *val.(*SweepTx))
+		return true
+	})
+
+	sort.Slice(sweepList, func(i, j int) bool {
+		return sweepList[i].Time.After(sweepList[j].Time)
+	})
+	return sweepList
+}
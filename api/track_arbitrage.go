@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
@@ -23,17 +24,19 @@ var txqueue sync.Map
 
 type UserProfitShareTx struct {
 	TxHash                   string
-	Initiated                bool      //We sent the user profit share TX to the node (e.g. we're waiting for inclusion in a block)
-	Committed                bool      //If the TX was committed in a block on chain (e.g. finished)
-	Succeeded                bool      //If the TX succeeded or failed
-	ArbitrageProfitsPending  sdk.Coins //We submitted a TX, waiting for block inclusion...
-	ArbitrageProfitsReceived sdk.Coins //Amount of arbitrage we sent to the user
+	Initiated                bool               //We sent the user profit share TX to the node (e.g. we're waiting for inclusion in a block)
+	Committed                bool               //If the TX was committed in a block on chain (e.g. finished)
+	Succeeded                bool               //If the TX succeeded or failed
+	Failure                  *osmosis.TxFailure //Why the TX failed, if it did
+	ArbitrageProfitsPending  sdk.Coins          //We submitted a TX, waiting for block inclusion...
+	ArbitrageProfitsReceived sdk.Coins          //Amount of arbitrage we sent to the user
 }
 
 type SubmittedTx struct {
 	TxHash    string
-	Committed bool //If the TX was committed in a block on chain (e.g. finished)
-	Succeeded bool //If the TX succeeded or failed
+	Committed bool               //If the TX was committed in a block on chain (e.g. finished)
+	Succeeded bool               //If the TX succeeded or failed
+	Failure   *osmosis.TxFailure //Why the TX failed, if it did
 	Swaps     []Swap
 }
 
@@ -45,6 +48,7 @@ type Swap struct {
 	IsHotWalletSwap bool //if this trade was performed using the hot wallet's funds
 	TokenIn         sdk.Coin
 	TokenOut        sdk.Coin
+	Hops            []osmosis.SwapHop //Every pool the swap traded through, in order
 }
 
 func GetQueuedAuthzTxSet(id string) (*AuthzArbitrageTxSet, error) {
@@ -96,66 +100,131 @@ func ExecuteQueuedZenith(lastChainHeight int64, _ int64) {
 
 	for _, zBlock := range pendingZBlocks {
 		if zBlock.Height > lastChainHeight && zBlock.IsZenithBlock {
-			rmList := []string{}
+			//Every queued request still waiting for a Zenith block can be bundled into the bid for this block
+			bundleReqs := []zenith.BundleRequest{}
+			zenithTxSets := map[string]*ZenithArbitrageTxSet{}
 
 			txqueue.Range(func(key any, val any) bool {
-				ok := false
 				zenithTxSet, ok := val.(*ZenithArbitrageTxSet)
-				if ok {
-					// Submit the TXs to a Zenith auction if:
-					// 1) They have not been submitted to an auction before, OR
-					// 2) They have been submitted before but didn't win the auction
-					zenithTxSet.LastChainHeight = lastChainHeight
-					awaitingZenithBlock := zenithTxSet.IsAwaitingZenithBlock()
-
-					if !awaitingZenithBlock {
-						fmt.Printf("Zenith request %+v is already being processed\n", zenithTxSet)
-						return true
-					}
+				if !ok {
+					return true
+				}
 
-					zenithBid := zenithTxSet.UserBidRequest
-					reqExpiration, _ := time.Parse(time.RFC3339, zenithBid.Expiration)
-					if reqExpiration.Before(zBlock.ProjectedBlocktime) {
-						fmt.Printf("Zenith request %+v expired, projected blocktime for the next Zenith block is %s\n", zenithBid, zBlock.ProjectedBlocktime)
-						return true
-					}
+				// Submit the TXs to a Zenith auction if:
+				// 1) They have not been submitted to an auction before, OR
+				// 2) They have been submitted before but didn't win the auction
+				zenithTxSet.LastChainHeight = lastChainHeight
+				if !zenithTxSet.IsAwaitingZenithBlock() {
+					return true
+				}
 
-					b64ZenithTxs, txs, arbFees, err := zenith.GetZenithBid(zBlock, *zenithBid, txClientSubmit)
-					if err == nil {
+				//The bid is replaced below, so its outcome has to be recorded first
+				zenithTxSet.recordLostBid()
 
-						bidReq := &zenith.ZenithBidRequest{
-							ChainID: zBlock.Auction.ChainID,
-							Height:  zBlock.Height,
-							Txs:     b64ZenithTxs,
-						}
+				zenithBid := zenithTxSet.UserBidRequest
+				reqExpiration, _ := time.Parse(time.RFC3339, zenithBid.Expiration)
+				if reqExpiration.Before(zBlock.ProjectedBlocktime) {
+					zenithTxSet.DroppedReason = "request expired before the next Zenith block"
+					config.Logger.Info("Zenith request expired", zap.String("id", key.(string)), zap.Time("projected blocktime", zBlock.ProjectedBlocktime))
+					return true
+				}
 
-						fmt.Printf("ZenithBidRequest %+v being submitted for Zenith request %+v\n", bidReq, zenithTxSet)
+				//The request may have been queued long ago, make sure the arbitrage still holds before bidding
+				err := osmosis.RefreshSimulation(txClientSubmit, &zenithBid.SimulatedSwap)
+				if errors.Is(err, osmosis.ErrStaleSimulation) {
+					zenithTxSet.DroppedReason = err.Error()
+					config.Logger.Info("Zenith request dropped", zap.String("id", key.(string)), zap.Error(err))
+					return true
+				} else if err != nil {
+					fmt.Printf("Issue refreshing Zenith request simulation, will retry: %s\n", err.Error())
+					return true
+				}
 
-						err = zenith.PlaceBid(bidReq)
-						zenithTxSet.ErrorPlacingBid = err != nil
-						zenithTxSet.HotWalletTxFees = sdk.NewCoins(sdk.NewCoin("uosmo", arbFees))
+				bundleReqs = append(bundleReqs, zenith.BundleRequest{Id: key.(string), Request: zenithBid})
+				zenithTxSets[key.(string)] = zenithTxSet
+				return true
+			})
 
-						if !zenithTxSet.ErrorPlacingBid {
-							zenithTxSet.SubmittedAuctionBid = bidReq
-							err = UpdateZenithTxSet(zenithTxSet, txs, txClientSubmit.TxConfig.TxDecoder(), zenithBid.SimulatedSwap.UserAddress, config.HotWalletAddress)
+			if len(bundleReqs) == 0 {
+				continue
+			}
 
-							if err != nil {
-								fmt.Println("Zenith: Tracking info may be unavailable for TX set due to unexpected error " + err.Error())
-							} else {
-								rmList = append(rmList, key.(string)) //allow it to be removed from the queue later
-							}
-						}
-					} else {
-						fmt.Printf("Issue in GetZenithBid(), failed to bid: %s\n", err.Error())
-					}
-				}
+			//Both kinds of bundle would sign the hot wallet's TXs from the same account sequence, so only one kind is bid on per height.
+			//Requests of the other kind wait for the next Zenith block.
+			bundle := bestZenithBundle(zBlock, bundleReqs, txClientSubmit)
+			if bundle != nil {
+				bidZenithBundle(zBlock, bundle, len(bundleReqs), zenithTxSets, txClientSubmit)
+			}
+		}
+	}
+}
 
-				return false
-			})
+// Bundles the queued requests of each kind, and returns the bundle that is expected to profit the most (nil if there is none)
+func bestZenithBundle(zBlock *zenith.FutureBlock, bundleReqs []zenith.BundleRequest, txClientSubmit client.Context) *zenith.Bundle {
+	var best *zenith.Bundle
+	for _, kind := range []string{zenith.BidKindTop, zenith.BidKindBlock} {
+		hasKind := false
+		for _, req := range bundleReqs {
+			hasKind = hasKind || req.Request.BidKind() == kind
+		}
+		if !hasKind {
+			continue
+		}
 
-			// for _, k := range rmList {
-			// 	txqueue.Delete(k)
-			// }
+		bundle, err := zenith.BuildBundle(zBlock, kind, bundleReqs, txClientSubmit)
+		if err != nil {
+			fmt.Printf("Issue in BuildBundle(), no %s bid: %s\n", kind, err.Error())
+			continue
+		}
+
+		if best == nil || bundle.ExpectedProfit().GT(best.ExpectedProfit()) {
+			best = bundle
+		}
+	}
+	return best
+}
+
+// Places the bid for the bundle, and tracks the bid for each request in it
+func bidZenithBundle(zBlock *zenith.FutureBlock, bundle *zenith.Bundle, queuedRequests int, zenithTxSets map[string]*ZenithArbitrageTxSet, txClientSubmit client.Context) {
+	bidReq := &zenith.ZenithBidRequest{
+		ChainID: zBlock.Auction.ChainID,
+		Height:  zBlock.Height,
+		Kind:    bundle.Kind,
+		Txs:     bundle.BidTxs,
+	}
+
+	fmt.Printf("ZenithBidRequest %+v being submitted for %d of %d queued Zenith requests\n", bidReq, len(bundle.Trades), queuedRequests)
+
+	bidId := ""
+	bidResp, bidErr := zenith.GetClient().PlaceBid(context.Background(), bidReq)
+	if bidErr != nil {
+		config.Logger.Error("Zenith bid failed", zap.Int64("height", bidReq.Height), zap.String("kind", bidReq.Kind), zap.Error(bidErr))
+	} else {
+		bidId = zenith.TrackBid(bidReq, bidResp, bundle.Txs, bundle.BidAmount)
+	}
+
+	bidStrategy := ""
+	if strategy, err := zenith.GetBidStrategy(); err == nil {
+		bidStrategy = strategy.Name()
+	}
+
+	//Each request only tracks its own TXs and pays its own share of the bid
+	for _, trade := range bundle.Trades {
+		zenithTxSet := zenithTxSets[trade.Id]
+		zenithTxSet.ErrorPlacingBid = bidErr != nil
+		zenithTxSet.HotWalletTxFees = sdk.NewCoins(sdk.NewCoin(osmosis.FeeDenom, trade.TotalArbFees))
+		if zenithTxSet.ErrorPlacingBid {
+			continue
+		}
+
+		zenithTxSet.SubmittedAuctionBid = bidReq
+		zenithTxSet.BidId = bidId
+		zenithTxSet.BidStrategy = bidStrategy
+		zenithTxSet.BundleSize = len(bundle.Trades)
+		zenithTxSet.UserBidRequest.SimulatedSwap = trade.Simulation
+		err := UpdateZenithTxSet(zenithTxSet, [][]byte{trade.UserTx, trade.HotWalletTx}, txClientSubmit.TxConfig.TxDecoder(), trade.Simulation.UserAddress, config.HotWalletAddress)
+		if err != nil {
+			fmt.Println("Zenith: Tracking info may be unavailable for TX set due to unexpected error " + err.Error())
 		}
 	}
 }
@@ -231,6 +300,7 @@ func UpdateZenithTxSet(zenithTxSet *ZenithArbitrageTxSet, txs [][]byte, txDecode
 	zenithTxSet.HotWalletAddress = hotWalletAddress
 	zenithTxSet.UserTxFees = sdk.Coins{}
 	zenithTxSet.TotalArbitrageRevenue = sdk.Coins{}
+	zenithTxSet.ArbitrageRevenues = nil
 	return nil
 }
 
@@ -239,6 +309,7 @@ func toSubmittedTx(parsedTx osmosis.OsmosisTx, userAddr string, hotWalletAddr st
 		TxHash:    parsedTx.Hash,
 		Committed: true,
 		Succeeded: parsedTx.IsSuccessfulTx,
+		Failure:   parsedTx.Failure,
 		Swaps:     []Swap{},
 	}
 
@@ -252,6 +323,7 @@ func toSubmittedTx(parsedTx osmosis.OsmosisTx, userAddr string, hotWalletAddr st
 				IsHotWalletSwap: swap.Address == hotWalletAddr,
 				TokenIn:         swap.TokenIn,
 				TokenOut:        swap.TokenOut,
+				Hops:            swap.Hops,
 			}
 			sTx.Swaps = append(sTx.Swaps, newSwap)
 		}
@@ -282,7 +354,17 @@ func queryOsmosisTxs(txs []SubmittedTx, txClientSearch client.Context) []osmosis
 		if err != nil {
 			fmt.Printf("Error %s looking up TX with hash %s\n", err.Error(), tx.TxHash)
 		} else {
-			parsedTx := osmosis.ParseRedpointSwaps(resp, tx.TxHash)
+			parsedTx := osmosis.ParseRedpointSwaps(resp, txClientSearch.Codec)
+			if parsedTx.Failure != nil {
+				config.Logger.Info("TX failed",
+					zap.String("tx hash", parsedTx.Hash),
+					zap.String("error code", parsedTx.Failure.ErrorCode),
+					zap.String("codespace", parsedTx.Failure.Codespace),
+					zap.Uint32("TX code", parsedTx.Failure.Code),
+					zap.Int("message index", parsedTx.Failure.MessageIndex),
+					zap.String("log", parsedTx.Failure.Log),
+				)
+			}
 			osmosisTxs = append(osmosisTxs, parsedTx)
 		}
 	}
@@ -305,6 +387,43 @@ func getArbTxHash(osmosisTxs []SubmittedTx) string {
 	return arbTxHash
 }
 
+// Subtracts the fees from the arbitrage revenue. Revenue and fees can be in several denoms, so both are priced in the fee denom
+// and the fees are taken from each revenue coin in proportion to its value. value prices coins in the fee denom (see valueInFeeDenom).
+func netArbitrageProfit(value func(sdk.Coins) (sdk.Int, error), revenue sdk.Coins, fees ...sdk.Coins) (profit sdk.Coins, isNegative bool) {
+	totalFees := sdk.Coins{}
+	for _, fee := range fees {
+		totalFees = totalFees.Add(fee...)
+	}
+	if totalFees.IsZero() {
+		return revenue, false
+	}
+
+	revenueValue, err := value(revenue)
+	if err != nil {
+		config.Logger.Error("Could not price the arbitrage revenue in the fee denom", zap.Error(err))
+		return revenue.SafeSub(totalFees)
+	}
+	feesValue, err := value(totalFees)
+	if err != nil {
+		config.Logger.Error("Could not price the arbitrage fees in the fee denom", zap.Error(err))
+		return revenue.SafeSub(totalFees)
+	}
+
+	if feesValue.GT(revenueValue) {
+		return sdk.Coins{}, true
+	} else if feesValue.Equal(revenueValue) {
+		return sdk.Coins{}, false
+	}
+
+	//Fraction of each revenue coin left after the fees
+	remaining := revenueValue.Sub(feesValue).ToDec().QuoInt(revenueValue)
+	profit = sdk.Coins{}
+	for _, coin := range revenue {
+		profit = profit.Add(sdk.NewCoin(coin.Denom, coin.Amount.ToDec().Mul(remaining).TruncateInt()))
+	}
+	return profit, false
+}
+
 // This function is called for every new block produced on the chain.
 // We check if there are TXs in our submittedtxs Map that completed on chain.
 // If so, we will log the expected vs. actual profits our Hot Wallet made.
@@ -323,12 +442,14 @@ func AuthzBlockNotificationHandler(chainHeight int64, _ int64) {
 			osmosisTxs := queryOsmosisTxs(authzTxSet.TradeTxs, txClientSearch)
 			if len(osmosisTxs) == len(authzTxSet.TradeTxs) {
 				authzTxSet.Committed = true
+				osmosis.InvalidatePools(authzTxSet.Simulation.PoolIds())
 			} else {
 				fmt.Printf("Waiting for TXs to finish: %s\n", getHashStr(authzTxSet.TradeTxs))
 				return true
 			}
 			authzTxSet.TradeTxs = []SubmittedTx{}
 			authzTxSet.HotWalletTxFees = sdk.Coins{}
+			authzTxSet.FeeGranterTxFees = sdk.Coins{}
 
 			//Handle TX fees and fees paid to Zenith (if applicable), record any swaps that happened
 			for _, parsedTx := range osmosisTxs {
@@ -339,6 +460,8 @@ func AuthzBlockNotificationHandler(chainHeight int64, _ int64) {
 					authzTxSet.UserTxFees = authzTxSet.UserTxFees.Add(parsedTx.Fees...)
 				} else if parsedTx.FeePayer == authzTxSet.HotWalletAddress {
 					authzTxSet.HotWalletTxFees = authzTxSet.HotWalletTxFees.Add(parsedTx.Fees...)
+				} else if parsedTx.FeeGranter != "" && parsedTx.FeePayer == config.Conf.Api.FeeGranterAddress {
+					authzTxSet.FeeGranterTxFees = authzTxSet.FeeGranterTxFees.Add(parsedTx.Fees...)
 				}
 
 				for _, swap := range submittedTx.Swaps {
@@ -356,7 +479,8 @@ func AuthzBlockNotificationHandler(chainHeight int64, _ int64) {
 						//Calculate the arbitrage profits
 						if swap.IsArbitrageSwap && swap.IsHotWalletSwap {
 							profit := swap.TokenOut.Sub(swap.TokenIn)
-							authzTxSet.TotalArbitrageRevenue.Add(profit)
+							authzTxSet.TotalArbitrageRevenue = authzTxSet.TotalArbitrageRevenue.Add(profit)
+							authzTxSet.ArbitrageRevenues = append(authzTxSet.ArbitrageRevenues, profit)
 						}
 					}
 				}
@@ -364,20 +488,25 @@ func AuthzBlockNotificationHandler(chainHeight int64, _ int64) {
 				authzTxSet.TradeTxs = append(authzTxSet.TradeTxs, submittedTx)
 			}
 		} else if ok && authzTxSet.Committed && !authzTxSet.UserProfitShareTx.Initiated {
+			//The profit share is sent after any Zenith auction the hot wallet's next sequence is held for
+			if osmosis.SequencesHeld(authzTxSet.HotWalletAddress) {
+				return true
+			}
 			authzTxSet.UserProfitShareTx.Initiated = true
 			allHash := getHashStr(authzTxSet.TradeTxs)
 			arbTxHash := getArbTxHash(authzTxSet.TradeTxs)
 
-			hotWalletProfit, isNegative := authzTxSet.TotalArbitrageRevenue.SafeSub(authzTxSet.HotWalletTxFees)
+			//Gas paid by the fee granter is still a cost of the arbitrage
+			hotWalletProfit, isNegative := netArbitrageProfit(feeDenomValuer(txClientSearch), authzTxSet.TotalArbitrageRevenue, authzTxSet.HotWalletTxFees, authzTxSet.FeeGranterTxFees)
 			authzTxSet.HotWalletArbitrageProfitActual = hotWalletProfit
 
 			//Print summary of TXs
 			fmt.Printf("Begin summary of TXs submitted by Redpoint backend. TX hashes: %s\n", allHash)
 			if !authzTxSet.TotalArbitrageRevenue.IsZero() && !isNegative {
 				fmt.Printf("Arbitrage revenue (actual): %s for TX '%s'\n", authzTxSet.TotalArbitrageRevenue, arbTxHash)
-				if authzTxSet.Simulation.HasArbitrageOpportunity {
-					fmt.Printf("Arbitrage revenue (estimated): %s for TX '%s'\n",
-						authzTxSet.Simulation.ArbitrageSwap.EstimatedProfitHumanReadable, arbTxHash)
+				for i, arbSwap := range authzTxSet.Simulation.GetArbitrageSwaps() {
+					fmt.Printf("Arbitrage revenue (estimated): %s for arbitrage swap %d in TX '%s'\n",
+						arbSwap.EstimatedProfitHumanReadable, i+1, arbTxHash)
 				}
 			} else {
 				fmt.Printf("TX set had no arbitrage, TX hash: %s\n", authzTxSet.TradeTxs[0].TxHash)
@@ -413,7 +542,7 @@ func AuthzBlockNotificationHandler(chainHeight int64, _ int64) {
 						ToAddress:   authzTxSet.UserAddress,
 						Amount:      sdk.Coins{tokenUserShare},
 					}
-					authzTxSet.UserProfitShareTx.ArbitrageProfitsPending.Add(tokenUserShare)
+					authzTxSet.UserProfitShareTx.ArbitrageProfitsPending = authzTxSet.UserProfitShareTx.ArbitrageProfitsPending.Add(tokenUserShare)
 					msgSends = append(msgSends, msgSendArbToUser)
 					fmt.Printf("Creating TX to send arb to user. Total arb: %s, user share: %s, user: %s\n", coin.String(), tokenUserShare.String(), authzTxSet.UserAddress)
 				} else {
@@ -422,7 +551,7 @@ func AuthzBlockNotificationHandler(chainHeight int64, _ int64) {
 			}
 
 			if len(msgSends) > 0 {
-				txClientSubmit, err := osmosis.GetOsmosisTxClient(conf.Api.ChainID, conf.GetApiRpcSubmitTxEndpoint(), conf.Api.KeyringHomeDir, conf.Api.KeyringBackend, conf.Api.HotWalletKey)
+				txClientSubmit, err := osmosis.GetOsmosisTxClient(conf.Api.ChainID, conf.GetApiRpcSubmitTxEndpoint(), conf.Api.KeyringHomeDir, conf.Api.KeyringBackend, conf.GetHotWalletKey(authzTxSet.HotWalletAddress))
 				if err != nil {
 					config.Logger.Error("GetOsmosisTxClient", zap.Error(err))
 					return true
@@ -446,8 +575,9 @@ func AuthzBlockNotificationHandler(chainHeight int64, _ int64) {
 			} else {
 				authzTxSet.UserProfitShareTx.Committed = true
 				authzTxSet.UserProfitShareTx.Succeeded = resp.TxResponse.Code == 0
+				authzTxSet.UserProfitShareTx.Failure = osmosis.ParseTxFailure(resp.TxResponse)
 				if authzTxSet.UserProfitShareTx.Succeeded {
-					parsedTx := osmosis.ParseRedpointSwaps(resp, authzTxSet.UserProfitShareTx.TxHash)
+					parsedTx := osmosis.ParseRedpointSwaps(resp, txClientSearch.Codec)
 					for _, msg := range parsedTx.Sends {
 						if msg.Receiver == authzTxSet.UserAddress {
 							coinsReceived = coinsReceived.Add(msg.Token)
@@ -482,6 +612,16 @@ func ParseZenithCommittedTxs(chainHeight int64, _ int64) {
 		if ok {
 			zenithTxSet.LastChainHeight = chainHeight
 
+			//Requests that expired without a bid that could still include them will never be bid on
+			if zenithTxSet.DroppedReason == "" && zenithTxSet.IsAwaitingZenithBlock() && zenithTxSet.IsExpired(time.Now()) {
+				zenithTxSet.DroppedReason = "request expired"
+			}
+
+			//Dropped requests have no TXs left to wait for
+			if zenithTxSet.DroppedReason != "" {
+				return true
+			}
+
 			if zenithTxSet.Committed && (zenithTxSet.SubmittedAuctionBid != nil &&
 				(zenithTxSet.LastChainHeight > zenithTxSet.SubmittedAuctionBid.Height)) {
 
@@ -496,16 +636,20 @@ func ParseZenithCommittedTxs(chainHeight int64, _ int64) {
 			}
 		}
 
+		//Look for the TXs until the grace period after the auction height is over, only then is the bid lost
 		if ok && !zenithTxSet.Committed && !zenithTxSet.IsAwaitingZenithBlock() {
 			osmosisTxs := queryOsmosisTxs(zenithTxSet.TradeTxs, txClientSearch)
 			if len(zenithTxSet.TradeTxs) != 0 && len(osmosisTxs) > 0 {
 				zenithTxSet.Committed = true
+				zenith.RecordBidOutcome(zenithTxSet.BidId, committedBidOutcome(zenithTxSet, osmosisTxs))
+				osmosis.InvalidatePools(zenithTxSet.Simulation.PoolIds())
 			} else {
 				fmt.Printf("Waiting for TXs to finish: %s\n", getHashStr(zenithTxSet.TradeTxs))
 				return true
 			}
 			zenithTxSet.TradeTxs = []SubmittedTx{}
 			zenithTxSet.HotWalletTxFees = sdk.Coins{}
+			zenithTxSet.FeeGranterTxFees = sdk.Coins{}
 
 			//Handle TX fees and fees paid to Zenith (if applicable), record any swaps that happened
 			for _, parsedTx := range osmosisTxs {
@@ -516,6 +660,8 @@ func ParseZenithCommittedTxs(chainHeight int64, _ int64) {
 					zenithTxSet.UserTxFees = zenithTxSet.UserTxFees.Add(parsedTx.Fees...)
 				} else if parsedTx.FeePayer == zenithTxSet.HotWalletAddress {
 					zenithTxSet.HotWalletTxFees = zenithTxSet.HotWalletTxFees.Add(parsedTx.Fees...)
+				} else if parsedTx.FeeGranter != "" && parsedTx.FeePayer == config.Conf.Api.FeeGranterAddress {
+					zenithTxSet.FeeGranterTxFees = zenithTxSet.FeeGranterTxFees.Add(parsedTx.Fees...)
 				}
 
 				for _, swap := range submittedTx.Swaps {
@@ -536,30 +682,38 @@ func ParseZenithCommittedTxs(chainHeight int64, _ int64) {
 						//Calculate the arbitrage profits
 						if swap.IsArbitrageSwap && swap.IsHotWalletSwap {
 							profit := swap.TokenOut.Sub(swap.TokenIn)
-							zenithTxSet.TotalArbitrageRevenue.Add(profit)
+							zenithTxSet.TotalArbitrageRevenue = zenithTxSet.TotalArbitrageRevenue.Add(profit)
+							zenithTxSet.ArbitrageRevenues = append(zenithTxSet.ArbitrageRevenues, profit)
 						}
 					}
 				}
 
 				zenithTxSet.TradeTxs = append(zenithTxSet.TradeTxs, submittedTx)
 			}
+		} else if ok && !zenithTxSet.Committed {
+			zenithTxSet.recordLostBid()
 		} else if ok && zenithTxSet.Committed && !zenithTxSet.UserProfitShareTx.Initiated {
+			//The profit share is sent after any Zenith auction the hot wallet's next sequence is held for
+			if osmosis.SequencesHeld(zenithTxSet.HotWalletAddress) {
+				return true
+			}
 			zenithTxSet.UserProfitShareTx.Initiated = true
 			allHash := getHashStr(zenithTxSet.TradeTxs)
 			arbTxHash := getArbTxHash(zenithTxSet.TradeTxs)
 
-			hotWalletProfit, _ := zenithTxSet.TotalArbitrageRevenue.SafeSub(zenithTxSet.HotWalletTxFees)
-			hotWalletProfit, isNegative := hotWalletProfit.SafeSub(zenithTxSet.HotWalletZenithFees)
+			//Gas paid by the fee granter is still a cost of the arbitrage
+			hotWalletProfit, isNegative := netArbitrageProfit(feeDenomValuer(txClientSearch), zenithTxSet.TotalArbitrageRevenue, zenithTxSet.HotWalletTxFees, zenithTxSet.FeeGranterTxFees, zenithTxSet.HotWalletZenithFees)
 			// hotWalletProfit, _ = hotWalletProfit.SafeSub(arbTxSet.UserProfitShareTx.UserArbitrageProfitsSent)
 			zenithTxSet.HotWalletArbitrageProfitActual = hotWalletProfit
+			recordBidProfit(txClientSearch, zenithTxSet)
 
 			//Print summary of TXs
 			fmt.Printf("Begin summary of TXs submitted by Redpoint backend. TX hashes: %s\n", allHash)
 			if !zenithTxSet.TotalArbitrageRevenue.IsZero() && !isNegative {
 				fmt.Printf("Arbitrage revenue (actual): %s for TX '%s'\n", zenithTxSet.TotalArbitrageRevenue, arbTxHash)
-				if zenithTxSet.Simulation.HasArbitrageOpportunity {
-					fmt.Printf("Arbitrage revenue (estimated): %s for TX '%s'\n",
-						zenithTxSet.Simulation.ArbitrageSwap.EstimatedProfitHumanReadable, arbTxHash)
+				for i, arbSwap := range zenithTxSet.Simulation.GetArbitrageSwaps() {
+					fmt.Printf("Arbitrage revenue (estimated): %s for arbitrage swap %d in TX '%s'\n",
+						arbSwap.EstimatedProfitHumanReadable, i+1, arbTxHash)
 				}
 			} else {
 				fmt.Printf("TX set had no arbitrage, TX hash: %s\n", zenithTxSet.TradeTxs[0].TxHash)
@@ -595,7 +749,7 @@ func ParseZenithCommittedTxs(chainHeight int64, _ int64) {
 						ToAddress:   zenithTxSet.UserAddress,
 						Amount:      sdk.Coins{tokenUserShare},
 					}
-					zenithTxSet.UserProfitShareTx.ArbitrageProfitsPending.Add(tokenUserShare)
+					zenithTxSet.UserProfitShareTx.ArbitrageProfitsPending = zenithTxSet.UserProfitShareTx.ArbitrageProfitsPending.Add(tokenUserShare)
 					msgSends = append(msgSends, msgSendArbToUser)
 					fmt.Printf("Creating TX to send arb to user. Total arb: %s, user share: %s, user: %s\n", coin.String(), tokenUserShare.String(), zenithTxSet.UserAddress)
 				} else {
@@ -604,7 +758,7 @@ func ParseZenithCommittedTxs(chainHeight int64, _ int64) {
 			}
 
 			if len(msgSends) > 0 {
-				txClientSubmit, err := osmosis.GetOsmosisTxClient(conf.Api.ChainID, conf.GetApiRpcSubmitTxEndpoint(), conf.Api.KeyringHomeDir, conf.Api.KeyringBackend, conf.Api.HotWalletKey)
+				txClientSubmit, err := osmosis.GetOsmosisTxClient(conf.Api.ChainID, conf.GetApiRpcSubmitTxEndpoint(), conf.Api.KeyringHomeDir, conf.Api.KeyringBackend, conf.GetHotWalletKey(zenithTxSet.HotWalletAddress))
 				if err != nil {
 					config.Logger.Error("GetOsmosisTxClient", zap.Error(err))
 					return true
@@ -628,8 +782,9 @@ func ParseZenithCommittedTxs(chainHeight int64, _ int64) {
 			} else {
 				zenithTxSet.UserProfitShareTx.Committed = true
 				zenithTxSet.UserProfitShareTx.Succeeded = resp.TxResponse.Code == 0
+				zenithTxSet.UserProfitShareTx.Failure = osmosis.ParseTxFailure(resp.TxResponse)
 				if zenithTxSet.UserProfitShareTx.Succeeded {
-					parsedTx := osmosis.ParseRedpointSwaps(resp, zenithTxSet.UserProfitShareTx.TxHash)
+					parsedTx := osmosis.ParseRedpointSwaps(resp, txClientSearch.Codec)
 					for _, msg := range parsedTx.Sends {
 						if msg.Receiver == zenithTxSet.UserAddress {
 							coinsReceived = coinsReceived.Add(msg.Token)
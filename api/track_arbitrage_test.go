@@ -0,0 +1,51 @@
+package api
+
+import (
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func TestNetArbitrageProfit(t *testing.T) {
+	//1 uatom is worth 10 uosmo, 1 uusdc is worth 2 uosmo
+	prices := map[string]int64{"uosmo": 1, "uatom": 10, "uusdc": 2}
+	value := func(coins sdk.Coins) (sdk.Int, error) {
+		total := sdk.ZeroInt()
+		for _, coin := range coins {
+			total = total.Add(coin.Amount.MulRaw(prices[coin.Denom]))
+		}
+		return total, nil
+	}
+	coins := func(s string) sdk.Coins {
+		parsed, err := sdk.ParseCoinsNormalized(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	cases := []struct {
+		name       string
+		revenue    sdk.Coins
+		fees       []sdk.Coins
+		expected   sdk.Coins
+		isNegative bool
+	}{
+		{"no fees", coins("1000uatom"), nil, coins("1000uatom"), false},
+		{"fees in the revenue denom", coins("1000uosmo"), []sdk.Coins{coins("100uosmo"), coins("50uosmo")}, coins("850uosmo"), false},
+		{"fees in the fee denom", coins("1000uatom"), []sdk.Coins{coins("2000uosmo")}, coins("800uatom"), false},
+		//Revenue is worth 1000 + 1000 uosmo, so the 500 uosmo fee takes a quarter of each coin
+		{"revenue in several denoms", coins("100uatom,500uusdc"), []sdk.Coins{coins("500uosmo")}, coins("75uatom,375uusdc"), false},
+		{"fees equal to the revenue", coins("100uatom,500uusdc"), []sdk.Coins{coins("1500uosmo"), coins("500uosmo")}, sdk.Coins{}, false},
+		{"fees above the revenue", coins("100uatom,500uusdc"), []sdk.Coins{coins("2001uosmo")}, sdk.Coins{}, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			profit, isNegative := netArbitrageProfit(value, c.revenue, c.fees...)
+			if !profit.IsEqual(c.expected) || isNegative != c.isNegative {
+				t.Errorf("profit is %s (negative: %t), expected %s (negative: %t)", profit, isNegative, c.expected, c.isNegative)
+			}
+		})
+	}
+}
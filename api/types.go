@@ -1,6 +1,9 @@
 package api
 
 import (
+	"time"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
 	"github.com/DefiantLabs/RedpointSwap/simulator"
 	"github.com/DefiantLabs/RedpointSwap/zenith"
 	sdk "github.com/cosmos/cosmos-sdk/types"
@@ -10,9 +13,22 @@ import (
 var Initialized bool
 
 type JWTClaim struct {
+	Grantee   string `json:"grantee,omitempty"`    //The hot wallet address the user's authz grant was issued to
+	GrantType string `json:"grant_type,omitempty"` //The msg type URL the user's authz grant allows, e.g. /osmosis.gamm.v1beta1.MsgSwapExactAmountIn
 	jwt.RegisteredClaims
 }
 
+// The hot wallet address the user's grant names. Tokens issued before we recorded the grantee don't have one,
+// their grants name the hot wallet we used back then, which is the retiring hot wallet during a key rotation.
+func (claims *JWTClaim) GranteeAddress() string {
+	if claims.Grantee != "" {
+		return claims.Grantee
+	} else if config.RetiringHotWalletAddress != "" {
+		return config.RetiringHotWalletAddress
+	}
+	return config.HotWalletAddress
+}
+
 var jwtKey []byte
 
 func SetSecretKey(jwtSecret string) {
@@ -23,9 +39,35 @@ func GetSecretKey() []byte {
 	return jwtKey
 }
 
+// Blocks after the auction height that we keep looking for a bid's TXs before the bid counts as lost (TX indexing can lag behind the chain)
+const bidOutcomeGraceBlocks = 3
+
 func (zenithTxSet *ZenithArbitrageTxSet) IsAwaitingZenithBlock() bool {
-	return zenithTxSet.SubmittedAuctionBid == nil ||
-		(zenithTxSet.LastChainHeight > zenithTxSet.SubmittedAuctionBid.Height && !zenithTxSet.Committed)
+	if zenithTxSet.DroppedReason != "" {
+		return false
+	}
+	return zenithTxSet.SubmittedAuctionBid == nil || (!zenithTxSet.bidOutstanding() && !zenithTxSet.Committed)
+}
+
+// The last bid's auction hasn't happened yet, or happened too recently to know whether the bid won
+func (zenithTxSet *ZenithArbitrageTxSet) bidOutstanding() bool {
+	bid := zenithTxSet.SubmittedAuctionBid
+	return bid != nil && zenithTxSet.LastChainHeight <= bid.Height+bidOutcomeGraceBlocks
+}
+
+// Records the last bid as lost if its TXs weren't committed within the grace period
+func (zenithTxSet *ZenithArbitrageTxSet) recordLostBid() {
+	if zenithTxSet.SubmittedAuctionBid != nil && !zenithTxSet.Committed && !zenithTxSet.bidOutstanding() {
+		zenith.RecordBidOutcome(zenithTxSet.BidId, zenith.BidOutcomeLost)
+	}
+}
+
+// The request expired before its TXs were committed, and no bid that could still include them is outstanding
+func (zenithTxSet *ZenithArbitrageTxSet) IsExpired(at time.Time) bool {
+	if zenithTxSet.Committed || zenithTxSet.UserBidRequest == nil {
+		return false
+	}
+	return !zenithTxSet.bidOutstanding() && zenithTxSet.UserBidRequest.TimeLeft(at) <= 0
 }
 
 func (zenithTxSet *ZenithArbitrageTxSet) SubmittedToAuction() bool {
@@ -44,6 +86,10 @@ type ZenithArbitrageTxSet struct {
 	UserBidRequest      *zenith.UserZenithRequest //The user's request including expiration, user TX, etc
 	SubmittedAuctionBid *zenith.ZenithBidRequest  //The last auction we bid on for this TX set
 	ErrorPlacingBid     bool                      //true if there was an error attempt
+	DroppedReason       string                    //Set if the request was dropped instead of bid on (e.g. the arbitrage no longer holds)
+	BidStrategy         string                    //The strategy that priced the last bid (see zenith.BidStrategy)
+	BidId               string                    //The last bid we placed for this TX set (see zenith.TrackBid)
+	BundleSize          int                       //Number of user requests in the last bid, including this one
 	HotWalletZenithFees sdk.Coins
 	SubmittedTxSet
 }
@@ -56,9 +102,11 @@ type SubmittedTxSet struct {
 	UserProfitShareTx              UserProfitShareTx //the TX that sends the user their portion of the arb earnings
 	TradeTxs                       []SubmittedTx     //includes user swap, arb swap, zenith payments
 	Simulation                     *simulator.SimulatedSwapResult
-	HotWalletTxFees                sdk.Coins //Total fees that the hot wallet paid for this TX set (Zenith fees and TX fees)
-	UserTxFees                     sdk.Coins //Total TX fees that the user paid for this TX set
-	TotalArbitrageRevenue          sdk.Coins //Total arbitrage revenue (does not include fees)
-	TotalArbitrageProfits          sdk.Coins //arbitrage revenue-fees paid by the hot wallet
-	HotWalletArbitrageProfitActual sdk.Coins //Arbitrage revenue-fees-amount we sent to the user
+	HotWalletTxFees                sdk.Coins  //Total fees that the hot wallet paid for this TX set (Zenith fees and TX fees)
+	FeeGranterTxFees               sdk.Coins  //TX fees for the hot wallet's TXs that were paid by the fee granter (see config FeeGranterAddress)
+	UserTxFees                     sdk.Coins  //Total TX fees that the user paid for this TX set
+	TotalArbitrageRevenue          sdk.Coins  //Total arbitrage revenue (does not include fees)
+	ArbitrageRevenues              []sdk.Coin //Revenue of each of the hot wallet's arbitrage swaps, in the order they executed
+	TotalArbitrageProfits          sdk.Coins  //arbitrage revenue-fees paid by the hot wallet
+	HotWalletArbitrageProfitActual sdk.Coins  //Arbitrage revenue-fees-amount we sent to the user
 }
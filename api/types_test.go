@@ -0,0 +1,35 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
+)
+
+func TestJWTClaimGranteeAddress(t *testing.T) {
+	hotWalletBefore, retiringBefore := config.HotWalletAddress, config.RetiringHotWalletAddress
+	defer func() { config.HotWalletAddress, config.RetiringHotWalletAddress = hotWalletBefore, retiringBefore }()
+	config.HotWalletAddress = "osmo1new"
+
+	cases := []struct {
+		name     string
+		grantee  string
+		retiring string
+		expected string
+	}{
+		{"grantee claim", "osmo1new", "osmo1old", "osmo1new"},
+		{"retiring grantee claim", "osmo1old", "osmo1old", "osmo1old"},
+		{"token without a grantee during a key rotation", "", "osmo1old", "osmo1old"},
+		{"token without a grantee", "", "", "osmo1new"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config.RetiringHotWalletAddress = c.retiring
+			claims := &JWTClaim{Grantee: c.grantee}
+			if grantee := claims.GranteeAddress(); grantee != c.expected {
+				t.Errorf("grantee is %s, expected %s", grantee, c.expected)
+			}
+		})
+	}
+}
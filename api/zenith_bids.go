@@ -0,0 +1,67 @@
+package api
+
+import (
+	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/DefiantLabs/RedpointSwap/osmosis"
+	"github.com/DefiantLabs/RedpointSwap/zenith"
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"go.uber.org/zap"
+)
+
+// Whether our last bid won the auction, or the TX set's TXs made it on chain some other way.
+// A winning bid puts every TX in the auction block.
+func committedBidOutcome(zenithTxSet *ZenithArbitrageTxSet, osmosisTxs []osmosis.OsmosisTx) string {
+	bidReq := zenithTxSet.SubmittedAuctionBid
+	if bidReq == nil || len(osmosisTxs) != len(zenithTxSet.TradeTxs) {
+		return zenith.BidOutcomeIncluded
+	}
+
+	for _, tx := range osmosisTxs {
+		if tx.Height != bidReq.Height {
+			return zenith.BidOutcomeIncluded
+		}
+	}
+
+	return zenith.BidOutcomeWon
+}
+
+// Record the hot wallet's profit after the bid and TX fees for the bid stats (in the fee denom)
+func recordBidProfit(queryClient client.Context, zenithTxSet *ZenithArbitrageTxSet) {
+	if zenithTxSet.BidId == "" {
+		return
+	}
+
+	revenue, err := valueInFeeDenom(queryClient, zenithTxSet.TotalArbitrageRevenue)
+	if err != nil {
+		config.Logger.Error("Could not price the arbitrage revenue in the fee denom", zap.Error(err))
+		return
+	}
+
+	fees, err := valueInFeeDenom(queryClient, zenithTxSet.HotWalletTxFees.Add(zenithTxSet.FeeGranterTxFees...).Add(zenithTxSet.HotWalletZenithFees...))
+	if err != nil {
+		config.Logger.Error("Could not price the hot wallet fees in the fee denom", zap.Error(err))
+		return
+	}
+
+	zenith.RecordBidProfit(zenithTxSet.BidId, revenue.Sub(fees))
+}
+
+func valueInFeeDenom(queryClient client.Context, coins sdk.Coins) (sdk.Int, error) {
+	total := sdk.ZeroInt()
+	for _, coin := range coins {
+		amount, err := osmosis.ToFeeDenom(queryClient, coin.Amount, coin.Denom)
+		if err != nil {
+			return total, err
+		}
+		total = total.Add(amount)
+	}
+	return total, nil
+}
+
+// Prices coins with valueInFeeDenom using the given query client
+func feeDenomValuer(queryClient client.Context) func(sdk.Coins) (sdk.Int, error) {
+	return func(coins sdk.Coins) (sdk.Int, error) {
+		return valueInFeeDenom(queryClient, coins)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	sdk "github.com/cosmos/cosmos-sdk/types"
@@ -16,13 +18,23 @@ var Logger *zap.Logger //Global logger
 var Conf Config        //Global config
 
 var HotWalletAddress string
-var HotWalletArbBalance sdk.Int
+var HotWalletArbBalances map[string]sdk.Int //k = arbitrage denom, v = balance
+
+// Only set while a hot wallet key rotation is in progress (see RetiringHotWalletKey)
+var RetiringHotWalletAddress string
+var RetiringHotWalletArbBalances map[string]sdk.Int
+
+// Guards the arbitrage balances once the app is running (they are refreshed when sweeps and the key rotation move funds)
+var hotWalletArbBalancesLock sync.RWMutex
 
 type Config struct {
-	Authz  authz
-	JWT    jwt
-	Zenith zenith
-	Api    api
+	Authz   authz
+	JWT     jwt
+	Zenith  zenith
+	Api     api
+	Sweep   sweep
+	Mempool mempool
+	Policy  policy
 }
 
 type jwt struct {
@@ -31,10 +43,54 @@ type jwt struct {
 }
 
 type zenith struct {
-	ZenithAuctionUrl string
-	ZenithBidUrl     string
-	MaximumBidAmount string  //Any valid Coin. Denom MUST match the zenith bid denom. This will cap the BidPercentage (see below).
-	BidPercentage    float64 //Float percentage of the arb profits that will be bid. Example: if arb profits are estimated as 10 OSMO, 0.1 will be 1 OSMO
+	ZenithAuctionUrl      string
+	ZenithBidUrl          string
+	MaximumBidAmount      string  //Any valid Coin. Denom MUST match the zenith bid denom. This will cap the BidPercentage (see below).
+	BidPercentage         float64 //Float percentage of the arb profits that will be bid. Example: if arb profits are estimated as 10 OSMO, 0.1 will be 1 OSMO
+	BidStrategy           string  //How bids are priced: "percentage" (BidPercentage of revenue, the default), "fixed" (FixedBidAmount) or "adaptive"
+	FixedBidAmount        string  //Any valid Coin in the bid denom. Bid for every block with the "fixed" strategy.
+	AdaptiveMinPercentage float64 //The "adaptive" strategy starts at BidPercentage, raises it by AdaptiveStep after each lost auction
+	AdaptiveMaxPercentage float64 //and lowers it by AdaptiveStep after each win, staying between the min and max percentages
+	AdaptiveStep          float64
+	AdaptiveUrgentSeconds int     //With the "adaptive" strategy, requests that expire within this many seconds bid AdaptiveMaxPercentage
+	RequestTimeoutMs      int     //Timeout for each request to the Zenith API
+	RequestRetries        uint    //Connection errors and 5xx responses from the Zenith API are retried this many times. 0 uses the default.
+	MaxBundleRequests     int     //Most queued user requests bundled into one Zenith bid. 0 uses the default.
+	BlockBidsEnabled      bool    //Allow requests to opt into bidding for the whole block (kind "block") instead of the top of the block
+	BlockBidMultiplier    float64 //Block bids bid this multiple of what the bid strategy bids for the top of the block. 0 uses the default.
+	MaximumBlockBidAmount string  //Any valid Coin in the bid denom. Caps block bids instead of MaximumBidAmount. Leave empty to use MaximumBidAmount.
+	LookaheadBlocks       int64   //Heights after the chain height checked for Zenith auctions, at most 10 (Zenith's max). 0 uses the default.
+	NegativeCacheMs       int64   //Heights Zenith says are too far in the future aren't queried again for this long. 0 uses the block time.
+}
+
+type sweep struct {
+	ColdWalletAddress   string //Hot wallet profits above the working capital target are sent to this address. Leave empty to disable sweeping.
+	WorkingCapital      string //Any valid Coins, e.g. 1000000000uosmo. Balances above this amount (plus pending payouts) will be swept.
+	SweepThreshold      string //Any valid Coins. If the sweepable amount reaches this threshold, sweep immediately (ignores SweepIntervalBlocks)
+	SweepIntervalBlocks int64  //Sweep any balance above the working capital every N blocks. 0 means only sweep when SweepThreshold is reached.
+}
+
+type mempool struct {
+	Enabled        bool    //Watch our RPC nodes' mempools for swaps we can back-run with arbitrage
+	PollIntervalMs int64   //How often to poll unconfirmed_txs. Defaults to 500.
+	MinPriceImpact float64 //Pending swaps that move prices less than this (e.g. .005 for .5%) are ignored
+	MinProfit      string  //Any valid Coin in the fee denom. Arbitrage must be estimated to earn at least this much after gas.
+	KillSwitchFile string  //While this file exists, no arbitrage is submitted for pending swaps (checked every poll)
+}
+
+type policy struct {
+	AllowedPools     []uint64 //If set, swaps may only trade through these pools
+	DeniedPools      []uint64 //Swaps may never trade through these pools
+	AllowedDenoms    []string //If set, swaps may only trade these denoms (e.g. to block scam tokenfactory denoms)
+	MinPoolLiquidity string   //Any valid Coins. If set, every pool traded through must hold at least this much of one of the denoms.
+	MaxHops          int      //Most pools a single swap may trade through. 0 uses the defaults (4 for user swaps, 5 for arbitrage).
+	MaxPriceImpact   float64  //Largest price impact allowed for a user's swap (e.g. .05 for 5%). 0 allows any price impact.
+}
+
+type ArbitrageCapital struct {
+	Denom       string
+	MinAmount   int64  //The hot wallet must hold at least this much of the denom on startup
+	PricePoolId uint64 //Pool used to price this denom in uosmo (the fee denom) for profitability checks. Not needed for uosmo.
 }
 
 type authz struct {
@@ -44,9 +100,12 @@ type authz struct {
 type api struct {
 	ChainID                   string
 	HotWalletKey              string
-	ArbitrageDenom            string //Right now, only uosmo is supported, so you must set this value to uosmo
-	ArbitrageDenomMinAmount   int64  //uosmo is 10^6, so 1000 OSMO == 1000000000
-	DefiantTrackingApi        string //All user and arbitrage trades are POSTed to this HTTP endpoint for invoicing & tracking usage
+	FeeGranterAddress         string             //Optional x/feegrant granter that pays gas for all TXs signed by HotWalletKey, so the arbitrage capital isn't spent on fees
+	RetiringHotWalletKey      string             //Previous hot wallet key during a key rotation. It finishes outstanding trades and payouts, then its funds are moved to HotWalletKey.
+	ArbitrageDenom            string             //Deprecated, use ArbitrageCapital. Only used if ArbitrageCapital is empty.
+	ArbitrageDenomMinAmount   int64              //uosmo is 10^6, so 1000 OSMO == 1000000000
+	ArbitrageCapital          []ArbitrageCapital //Denoms the hot wallet holds for arbitrage. Arbitrage cycles must start and end in one of these denoms.
+	DefiantTrackingApi        string             //All user and arbitrage trades are POSTed to this HTTP endpoint for invoicing & tracking usage
 	LogPath                   string
 	LogLevel                  string
 	AllowedCORSDomains        string
@@ -58,6 +117,138 @@ type api struct {
 	RpcSearchEndpoints        string //Nodes where we can SEARCH Txs. Comma separated.
 	WebsocketEndpoints        string //comma separated. this should be something like rpc.osmosis.zone:443 (no protocol prefix)
 	UserProfitSharePercentage float64
+	CacheAllPools             bool    //Refresh every gamm pool each block. Otherwise only pools on recently used routes are refreshed.
+	SimulationTolerance       float64 //Max relative difference between a client's simulated amounts and our recomputation (e.g. .01 is 1%). Defaults to .01.
+}
+
+// GetSimulationTolerance Max relative difference allowed between client simulations and our own
+func (conf *Config) GetSimulationTolerance() float64 {
+	if conf.Api.SimulationTolerance <= 0 {
+		return 0.01
+	}
+	return conf.Api.SimulationTolerance
+}
+
+// GetMempoolPollInterval How often to poll our RPC nodes for pending swaps
+func (conf *Config) GetMempoolPollInterval() time.Duration {
+	if conf.Mempool.PollIntervalMs <= 0 {
+		return 500 * time.Millisecond
+	}
+	return time.Duration(conf.Mempool.PollIntervalMs) * time.Millisecond
+}
+
+// GetZenithRequestTimeout Timeout for each request to the Zenith API
+func (conf *Config) GetZenithRequestTimeout() time.Duration {
+	if conf.Zenith.RequestTimeoutMs <= 0 {
+		return 3 * time.Second
+	}
+	return time.Duration(conf.Zenith.RequestTimeoutMs) * time.Millisecond
+}
+
+// GetZenithRequestRetries How many times failed requests to the Zenith API are retried
+func (conf *Config) GetZenithRequestRetries() uint {
+	if conf.Zenith.RequestRetries == 0 {
+		return 2
+	}
+	return conf.Zenith.RequestRetries
+}
+
+// GetZenithMaxBundleRequests Most queued user requests bundled into one Zenith bid
+func (conf *Config) GetZenithMaxBundleRequests() int {
+	if conf.Zenith.MaxBundleRequests <= 0 {
+		return 5
+	}
+	return conf.Zenith.MaxBundleRequests
+}
+
+// GetZenithBlockBidMultiplier Multiple of the top of block bid that is bid for a whole block
+func (conf *Config) GetZenithBlockBidMultiplier() float64 {
+	if conf.Zenith.BlockBidMultiplier <= 0 {
+		return 2
+	}
+	return conf.Zenith.BlockBidMultiplier
+}
+
+// GetZenithMaximumBidAmount The most we bid for the kind of Zenith bid ("top" or "block")
+func (conf *Config) GetZenithMaximumBidAmount(kind string) string {
+	if kind == "block" && conf.Zenith.MaximumBlockBidAmount != "" {
+		return conf.Zenith.MaximumBlockBidAmount
+	}
+	return conf.Zenith.MaximumBidAmount
+}
+
+// GetZenithLookaheadBlocks How many heights after the chain height are checked for Zenith auctions
+func (conf *Config) GetZenithLookaheadBlocks() int64 {
+	if conf.Zenith.LookaheadBlocks <= 0 {
+		return 5
+	} else if conf.Zenith.LookaheadBlocks > 10 {
+		return 10 //Zenith doesn't hold auctions further ahead
+	}
+	return conf.Zenith.LookaheadBlocks
+}
+
+// GetZenithNegativeCacheTtl How long a height Zenith says is too far in the future is cached before it's queried again
+func (conf *Config) GetZenithNegativeCacheTtl(blockTime time.Duration) time.Duration {
+	if conf.Zenith.NegativeCacheMs <= 0 {
+		return blockTime
+	}
+	return time.Duration(conf.Zenith.NegativeCacheMs) * time.Millisecond
+}
+
+// GetHotWalletKey Key that signs for the given hot wallet address. New trades should always use HotWalletKey,
+// the retiring key is only used for grants and trades that name the retiring address.
+func (conf *Config) GetHotWalletKey(hotWalletAddress string) string {
+	if conf.Api.RetiringHotWalletKey != "" && hotWalletAddress != "" && hotWalletAddress == RetiringHotWalletAddress {
+		return conf.Api.RetiringHotWalletKey
+	}
+	return conf.Api.HotWalletKey
+}
+
+// GetHotWalletArbBalance Arbitrage balance of the given hot wallet address in the given denom
+func GetHotWalletArbBalance(hotWalletAddress string, denom string) sdk.Int {
+	hotWalletArbBalancesLock.RLock()
+	defer hotWalletArbBalancesLock.RUnlock()
+
+	balances := HotWalletArbBalances
+	if hotWalletAddress != "" && hotWalletAddress == RetiringHotWalletAddress {
+		balances = RetiringHotWalletArbBalances
+	}
+
+	balance, ok := balances[denom]
+	if !ok {
+		return sdk.ZeroInt()
+	}
+	return balance
+}
+
+// SetHotWalletArbBalances Replaces the arbitrage balances of the given hot wallet address
+func SetHotWalletArbBalances(hotWalletAddress string, balances map[string]sdk.Int) {
+	hotWalletArbBalancesLock.Lock()
+	defer hotWalletArbBalancesLock.Unlock()
+
+	if hotWalletAddress != "" && hotWalletAddress == RetiringHotWalletAddress {
+		RetiringHotWalletArbBalances = balances
+	} else if hotWalletAddress == HotWalletAddress {
+		HotWalletArbBalances = balances
+	}
+}
+
+// GetArbitrageCapital All denoms the hot wallet can use for arbitrage
+func (conf *Config) GetArbitrageCapital() []ArbitrageCapital {
+	if len(conf.Api.ArbitrageCapital) == 0 && conf.Api.ArbitrageDenom != "" {
+		return []ArbitrageCapital{{Denom: conf.Api.ArbitrageDenom, MinAmount: conf.Api.ArbitrageDenomMinAmount}}
+	}
+	return conf.Api.ArbitrageCapital
+}
+
+// GetArbitrageCapitalForDenom Returns false if the hot wallet doesn't hold the given denom for arbitrage
+func (conf *Config) GetArbitrageCapitalForDenom(denom string) (ArbitrageCapital, bool) {
+	for _, capital := range conf.GetArbitrageCapital() {
+		if capital.Denom == denom {
+			return capital, true
+		}
+	}
+	return ArbitrageCapital{}, false
 }
 
 var lastWebsocketEndpointIndex = 0
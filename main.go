@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/DefiantLabs/RedpointSwap/api"
 	"github.com/DefiantLabs/RedpointSwap/api/middleware"
@@ -61,13 +62,53 @@ func main() {
 		config.Logger.Fatal("GetAccountBalances", zap.Error(err))
 	}
 
-	arbWalletBalanceRequired := sdk.NewCoin(config.Conf.Api.ArbitrageDenom, sdk.NewInt(config.Conf.Api.ArbitrageDenomMinAmount))
-	arbWalletBalanceActual := osmosis.GetTokenBalance(config.Conf.Api.ArbitrageDenom, hotWalletBalances)
-	if !arbWalletBalanceActual.GTE(arbWalletBalanceRequired.Amount) {
-		config.Logger.Fatal("Hot wallet insufficient balance", zap.String("Required balance", arbWalletBalanceRequired.String()))
+	if len(config.Conf.GetArbitrageCapital()) == 0 {
+		config.Logger.Fatal("No arbitrage capital configured")
+	}
+
+	config.HotWalletArbBalances = map[string]sdk.Int{}
+	for _, capital := range config.Conf.GetArbitrageCapital() {
+		arbWalletBalanceRequired := sdk.NewCoin(capital.Denom, sdk.NewInt(capital.MinAmount))
+		arbWalletBalanceActual := osmosis.GetTokenBalance(capital.Denom, hotWalletBalances)
+		if !arbWalletBalanceActual.GTE(arbWalletBalanceRequired.Amount) {
+			config.Logger.Fatal("Hot wallet insufficient balance", zap.String("Required balance", arbWalletBalanceRequired.String()))
+		} else if capital.Denom != osmosis.FeeDenom && capital.PricePoolId == 0 {
+			config.Logger.Fatal("Arbitrage capital needs a pricing pool", zap.String("denom", capital.Denom))
+		}
+
+		config.HotWalletArbBalances[capital.Denom] = arbWalletBalanceActual
+	}
+
+	//The fee granter pays gas for the hot wallet, make sure the grant exists so TXs don't fail later
+	if config.Conf.Api.FeeGranterAddress != "" {
+		err = osmosis.HasFeeAllowance(txClient, config.Conf.Api.FeeGranterAddress, config.HotWalletAddress)
+		if err != nil {
+			config.Logger.Fatal("Fee granter has no allowance for hot wallet", zap.String("fee granter", config.Conf.Api.FeeGranterAddress), zap.Error(err))
+		}
+	}
+
+	//During a key rotation the old hot wallet keeps working for grants and trades that name its address
+	if config.Conf.Api.RetiringHotWalletKey != "" {
+		retiringAddr, err := osmosis.GetKeyAddressForKey(config.Conf.Api.ChainID, config.Conf.GetApiRpcSearchTxEndpoint(),
+			config.Conf.Api.KeyringHomeDir, config.Conf.Api.KeyringBackend, config.Conf.Api.RetiringHotWalletKey)
+		if err != nil {
+			config.Logger.Fatal("GetKeyAddressForKey (retiring hot wallet key)", zap.Error(err))
+		}
+
+		retiringBalances, err := osmosis.GetAccountBalances(txClient, retiringAddr)
+		if err != nil {
+			config.Logger.Fatal("GetAccountBalances (retiring hot wallet key)", zap.Error(err))
+		}
+
+		config.RetiringHotWalletAddress = retiringAddr
+		config.RetiringHotWalletArbBalances = map[string]sdk.Int{}
+		for _, capital := range config.Conf.GetArbitrageCapital() {
+			config.RetiringHotWalletArbBalances[capital.Denom] = osmosis.GetTokenBalance(capital.Denom, retiringBalances)
+		}
+		api.StartKeyRotation(time.Now())
+		config.Logger.Info("Hot wallet key rotation in progress", zap.String("retiring address", retiringAddr), zap.String("new address", addr))
 	}
 
-	config.HotWalletArbBalance = arbWalletBalanceActual
 	newBlocks := make(chan int64)
 	done := make(chan struct{})
 
@@ -80,9 +121,12 @@ func main() {
 	//Track average time between blocks and notify Zenith when a new block is available
 	go func() {
 		defer close(done)
-		osmosis.ProcessNewBlock(newBlocks, []func(int64, int64){zenith.ZenithBlockNotificationHandler, api.AuthzBlockNotificationHandler, api.ExecuteQueuedZenith, api.ParseZenithCommittedTxs})
+		osmosis.ProcessNewBlock(newBlocks, []func(int64, int64){osmosis.PoolCacheBlockNotificationHandler, zenith.ZenithBlockNotificationHandler, api.AuthzBlockNotificationHandler, api.ExecuteQueuedZenith, api.ParseZenithCommittedTxs, api.SweepBlockNotificationHandler, api.RotationBlockNotificationHandler})
 	}()
 
+	//Back-run pending swaps in our RPC nodes' mempools (if enabled)
+	go api.MonitorMempool()
+
 	go func() {
 		defer close(done)
 		middleware.InitializeRestApi()
@@ -5,8 +5,8 @@ import (
 	"fmt"
 
 	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/DefiantLabs/RedpointSwap/simulator"
 	"github.com/cosmos/cosmos-sdk/client"
-	"github.com/cosmos/cosmos-sdk/client/tx"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	gammTypes "github.com/osmosis-labs/osmosis/v13/x/gamm/types"
 	"go.uber.org/zap"
@@ -17,30 +17,199 @@ func GetSignedTx(
 	msgs []sdk.Msg,
 	txGas uint64,
 ) ([]byte, error) {
-	txf := BuildTxFactory(txClient, txGas)
-	txf, txfErr := PrepareFactory(txClient, txClient.GetFromName(), txf)
-	if txfErr != nil {
-		return nil, txfErr
+	return GetSignedTxAtSequence(txClient, msgs, txGas, 0, 0)
+}
+
+// Signs the TX with the account's next sequence plus the offset, so several TXs from the same account
+// can be signed before any of them are committed (e.g. for a Zenith bundle). The TXs must execute in sequence order.
+// If holdUntilHeight is set (e.g. the auction height of a Zenith bundle), no other TX is broadcast from the account
+// until the block at that height is committed, since it would use the same sequence.
+func GetSignedTxAtSequence(
+	txClient client.Context,
+	msgs []sdk.Msg,
+	txGas uint64,
+	sequenceOffset uint64,
+	holdUntilHeight int64,
+) ([]byte, error) {
+	signingLock.Lock()
+	defer signingLock.Unlock()
+
+	txBytes, _, err := signTx(txClient, msgs, txGas, 0, sequenceOffset)
+	if err == nil && holdUntilHeight > 0 {
+		holdSequences(txClient, holdUntilHeight)
+	}
+	return txBytes, err
+}
+
+var ErrPoolDataUnavailable = errors.New("pool data unavailable")
+
+// Recomputes the simulated user swap and arbitrage with on-chain pool state (see simulator.VerifySimulation).
+// On success, the simulation's amounts out are replaced with the recomputed amounts, and its height with the height of the pool state.
+func VerifySimulation(queryClient client.Context, result *simulator.SimulatedSwapResult) error {
+	pools, err := GetPoolStates(queryClient, result.PoolIds())
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPoolDataUnavailable, err.Error())
+	}
+
+	err = simulator.VerifySimulation(pools, result, config.Conf.GetSimulationTolerance())
+	if err != nil {
+		return err
+	}
+
+	result.SimulationHeight = pools.Height()
+	return nil
+}
+
+// Builds the hot wallet's arbitrage swaps, in order, as MsgSwapExactAmountIn or MsgSwapExactAmountOut depending on the simulation.
+// The user's swap executes first and each arbitrage moves the pools for the next one, so every MsgSwapExactAmountIn
+// is sized against the pools as they will be when it executes. Arbitrage that is no longer profitable on its own is skipped.
+func BuildArbitrages(txClient client.Context, userSwap *simulator.SimulatedSwap, arbSwaps []*simulator.SimulatedSwap) ([]sdk.Msg, error) {
+	return BuildArbitragesOnPools(txClient, sizingPools(txClient, userSwap, arbSwaps), arbSwaps)
+}
+
+// Same as BuildArbitrages, but the arbitrage is sized against the given pools (e.g. after the earlier trades in a Zenith bundle).
+// The pools are updated as each arbitrage swap executes. Nil pools fall back to the requested arbitrage amounts.
+func BuildArbitragesOnPools(txClient client.Context, pools simulator.PoolStates, arbSwaps []*simulator.SimulatedSwap) ([]sdk.Msg, error) {
+	msgs := []sdk.Msg{}
+
+	for _, arbSwap := range arbSwaps {
+		var arbMsgs []sdk.Msg
+		var err error
+		amountIn := arbSwap.GetTokenIn().Amount
+
+		if arbSwap.IsExactAmountOut() {
+			arbMsgs, err = BuildArbitrageSwapExactAmountOut(txClient, arbSwap.TokenOut, arbSwap.TokenInMaxAmount, arbSwap.OutRoutes)
+		} else {
+			var tokenIn sdk.Coin
+			tokenIn, err = SizeArbitrageSwap(txClient, pools, arbSwap.TokenIn, arbSwap.Routes)
+			if errors.Is(err, simulator.ErrUnprofitableArbitrage) {
+				config.Logger.Info("Skipping unprofitable arbitrage swap", zap.String("pools", arbSwap.Pools), zap.String("token in", arbSwap.TokenIn.String()))
+				continue
+			} else if err == nil {
+				amountIn = tokenIn.Amount
+				arbMsgs, err = BuildArbitrageSwap(txClient, tokenIn, arbSwap.Routes)
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, arbMsgs...)
+
+		//The next arbitrage trades against the pools after this one
+		if pools != nil {
+			amountInFloat, err := amountIn.ToDec().Float64()
+			if err == nil && arbSwap.IsExactAmountOut() {
+				amountInFloat, err = pools.SwapAmountIn(arbSwap)
+			}
+			if err == nil {
+				err = pools.ApplyRoute(arbSwap.Hops(), amountInFloat)
+			}
+			if err != nil {
+				config.Logger.Warn("Could not simulate arbitrage swap, using requested amounts for the remaining arbitrage", zap.Error(err))
+				pools = nil
+			}
+		}
+	}
+
+	if len(msgs) == 0 {
+		return nil, simulator.ErrUnprofitableArbitrage
+	}
+	return msgs, nil
+}
+
+// The pools the arbitrage swaps trade through, as they will be after the user's swap. Nil if the pool data is unavailable.
+func sizingPools(queryClient client.Context, userSwap *simulator.SimulatedSwap, arbSwaps []*simulator.SimulatedSwap) simulator.PoolStates {
+	poolIds := []uint64{}
+	for _, arbSwap := range arbSwaps {
+		poolIds = append(poolIds, arbSwap.PoolIds()...)
+	}
+	if userSwap != nil {
+		poolIds = append(poolIds, userSwap.PoolIds()...)
 	}
-	txBuilder, err := tx.BuildUnsignedTx(txf, msgs...)
+
+	pools, err := GetPoolStates(queryClient, poolIds)
 	if err != nil {
-		return nil, err
+		config.Logger.Warn("Pool data unavailable, using requested arbitrage amounts", zap.Error(err))
+		return nil
+	}
+
+	if userSwap != nil {
+		userAmountIn, err := pools.SwapAmountIn(userSwap)
+		if err == nil {
+			err = pools.ApplyRoute(userSwap.Hops(), userAmountIn)
+		}
+		if err != nil {
+			config.Logger.Warn("Could not simulate user swap, using requested arbitrage amounts", zap.Error(err))
+			return nil
+		}
 	}
-	err = tx.Sign(txf, txClient.GetFromName(), txBuilder, true)
+
+	return pools
+}
+
+// Computes the most profitable amount in for the arbitrage from the pool reserves, capped by the hot wallet balance and pool liquidity.
+// Falls back to the requested amount (capped by the hot wallet balance) if the pool data is unavailable (nil pools).
+func SizeArbitrageSwap(queryClient client.Context, pools simulator.PoolStates, tokenIn sdk.Coin, routes gammTypes.SwapAmountInRoutes) (sdk.Coin, error) {
+	arbWalletBalance := config.GetHotWalletArbBalance(queryClient.GetFromAddress().String(), tokenIn.Denom)
+	if pools == nil {
+		fallback := tokenIn
+		if fallback.Amount.GT(arbWalletBalance) {
+			fallback.Amount = arbWalletBalance
+		}
+		return fallback, nil
+	}
+
+	amountIn, amountOut, err := simulator.OptimalArbitrageAmount(pools, simulator.HopsFromRoutes(tokenIn.Denom, routes), arbWalletBalance)
 	if err != nil {
-		return nil, err
+		return sdk.Coin{}, err
+	}
+
+	config.Logger.Info("Sized arbitrage swap",
+		zap.String("requested", tokenIn.String()),
+		zap.String("sized", sdk.NewCoin(tokenIn.Denom, amountIn).String()),
+		zap.String("estimated out", amountOut.String()),
+	)
+	return sdk.NewCoin(tokenIn.Denom, amountIn), nil
+}
+
+// An exact amount out arbitrage receives tokenOut and pays at most tokenInMaxAmt of the same denom.
+func BuildArbitrageSwapExactAmountOut(txClient client.Context, tokenOut sdk.Coin, tokenInMaxAmt sdk.Int, routes gammTypes.SwapAmountOutRoutes) ([]sdk.Msg, error) {
+	arbWalletBalance := config.GetHotWalletArbBalance(txClient.GetFromAddress().String(), tokenOut.Denom)
+
+	if _, ok := config.Conf.GetArbitrageCapitalForDenom(tokenOut.Denom); !ok {
+		return nil, fmt.Errorf("hot wallet does not hold %s for arbitrage", tokenOut.Denom)
+	} else if len(routes) == 0 {
+		return nil, errors.New("no arbitrage routes in request")
+	} else if routes[0].TokenInDenom != tokenOut.Denom { //Verify that the first route's denom in matches the token out denom (arb trade)
+		config.Logger.Error("Invalid arbitrage trade",
+			zap.String("token out", tokenOut.String()),
+			zap.String("first route in denom", routes[0].TokenInDenom),
+		)
+		return nil, fmt.Errorf("invalid arbitrage trade, token out %s does not match denom in %s", tokenOut.String(), routes[0].TokenInDenom)
+	} else if tokenInMaxAmt.IsNil() || !tokenInMaxAmt.IsPositive() {
+		return nil, errors.New("invalid arbitrage trade, token in max amount must be positive")
 	}
 
-	return txClient.TxConfig.TxEncoder()(txBuilder.GetTx())
+	//Note that the maximum amount in can never exceed the amount out. This prevents swaps where the hot wallet loses funds (excluding fees)
+	if tokenInMaxAmt.GT(tokenOut.Amount) {
+		tokenInMaxAmt = tokenOut.Amount
+	}
+
+	if tokenInMaxAmt.GT(arbWalletBalance) {
+		return nil, fmt.Errorf("arbitrage max amount in %s%s exceeds hot wallet balance", tokenInMaxAmt, tokenOut.Denom)
+	}
+
+	return []sdk.Msg{BuildSwapExactAmountOut(tokenOut, tokenInMaxAmt, routes, txClient.GetFromAddress().String())}, nil
 }
 
+// Builds a single MsgSwapExactAmountIn for the arbitrage. The amount in should already be sized (see SizeArbitrageSwap).
 func BuildArbitrageSwap(txClient client.Context, tokenIn sdk.Coin, routes gammTypes.SwapAmountInRoutes) ([]sdk.Msg, error) {
-	arbs := []sdk.Msg{}
-	amountRemaining := tokenIn.Amount
-	totalMsgs := 0
-	arbWalletBalance := config.HotWalletArbBalance
+	arbWalletBalance := config.GetHotWalletArbBalance(txClient.GetFromAddress().String(), tokenIn.Denom)
 
-	if len(routes) == 0 {
+	if _, ok := config.Conf.GetArbitrageCapitalForDenom(tokenIn.Denom); !ok {
+		return nil, fmt.Errorf("hot wallet does not hold %s for arbitrage", tokenIn.Denom)
+	} else if len(routes) == 0 {
 		return nil, errors.New("no arbitrage routes in request")
 	} else if routes[len(routes)-1].TokenOutDenom != tokenIn.Denom { //Verify that the token denom in matches the last route's denom out (arb trade)
 		lastRouteOutDenom := routes[len(routes)-1].TokenOutDenom
@@ -49,30 +218,28 @@ func BuildArbitrageSwap(txClient client.Context, tokenIn sdk.Coin, routes gammTy
 			zap.String("last route out denom", lastRouteOutDenom),
 		)
 		return nil, fmt.Errorf("invalid arbitrage trade, token in %s does not match denom out %s", tokenIn.String(), lastRouteOutDenom)
+	} else if !tokenIn.Amount.IsPositive() {
+		return nil, errors.New("invalid arbitrage trade, token in amount must be positive")
+	} else if tokenIn.Amount.GT(arbWalletBalance) {
+		return nil, fmt.Errorf("arbitrage amount in %s exceeds hot wallet balance", tokenIn.String())
 	}
 
-	for amountRemaining.GT(sdk.ZeroInt()) && totalMsgs < 25 {
-		tokenIn := sdk.NewCoin(tokenIn.Denom, amountRemaining)
-		if amountRemaining.GT(arbWalletBalance) {
-			tokenIn.Amount = arbWalletBalance
-		}
-
-		amountRemaining = amountRemaining.Sub(tokenIn.Amount)
-
-		//Note that the minimum amount out is the same as token in. This prevents swaps where the hot wallet loses funds (excluding fees)
-		tokenOutMinAmt := tokenIn.Amount
-		arbs = append(arbs, BuildSwapExactAmountIn(tokenIn, tokenOutMinAmt, routes, txClient.GetFromAddress().String()))
-		totalMsgs += 1
-	}
-
-	return arbs, nil
+	//Note that the minimum amount out is the same as token in. This prevents swaps where the hot wallet loses funds (excluding fees)
+	tokenOutMinAmt := tokenIn.Amount
+	return []sdk.Msg{BuildSwapExactAmountIn(tokenIn, tokenOutMinAmt, routes, txClient.GetFromAddress().String())}, nil
 }
 
-func EstimateArbGas(tokenIn sdk.Coin, routes gammTypes.SwapAmountInRoutes) (uint64, error) {
-	amountRemaining := tokenIn.Amount
-	totalMsgs := 0
-	arbWalletBalance := config.HotWalletArbBalance
+// The arbitrage is always a single swap message
+func EstimateArbGas(arbSwap *simulator.SimulatedSwap) (uint64, error) {
+	if arbSwap.IsExactAmountOut() {
+		if len(arbSwap.OutRoutes) == 0 || arbSwap.OutRoutes[0].TokenInDenom != arbSwap.TokenOut.Denom {
+			return 0, fmt.Errorf("invalid arbitrage trade, token out %s does not match first route denom in", arbSwap.TokenOut.String())
+		}
+		return GetGasFee(len(arbSwap.OutRoutes)), nil
+	}
 
+	tokenIn := arbSwap.TokenIn
+	routes := arbSwap.Routes
 	if len(routes) == 0 {
 		return 0, errors.New("no arbitrage routes in request")
 	} else if routes[len(routes)-1].TokenOutDenom != tokenIn.Denom { //Verify that the token denom in matches the last route's denom out (arb trade)
@@ -84,15 +251,5 @@ func EstimateArbGas(tokenIn sdk.Coin, routes gammTypes.SwapAmountInRoutes) (uint
 		return 0, fmt.Errorf("invalid arbitrage trade, token in %s does not match denom out %s", tokenIn.String(), lastRouteOutDenom)
 	}
 
-	for amountRemaining.GT(sdk.ZeroInt()) && totalMsgs < 25 {
-		tokenIn := sdk.NewCoin(tokenIn.Denom, amountRemaining)
-		if amountRemaining.GT(arbWalletBalance) {
-			tokenIn.Amount = arbWalletBalance
-		}
-
-		amountRemaining = amountRemaining.Sub(tokenIn.Amount)
-		totalMsgs += 1
-	}
-	gasFee := GetGasFee(len(routes) * totalMsgs)
-	return gasFee, nil
+	return GetGasFee(len(routes)), nil
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/DefiantLabs/RedpointSwap/config"
 	"github.com/avast/retry-go"
 	"github.com/cosmos/cosmos-sdk/client"
 	"github.com/cosmos/cosmos-sdk/client/flags"
@@ -90,64 +91,95 @@ func GetOsmosisTxClient(chain string, node string, osmosisHomeDir string, keyrin
 		WithClient(rpcClient).
 		WithSkipConfirmation(true)
 
+	//Gas for the hot wallet's TXs is paid by a separate fee granter account (if configured)
+	if config.Conf.Api.FeeGranterAddress != "" && fromFlag == config.Conf.Api.HotWalletKey {
+		granter, err := sdk.AccAddressFromBech32(config.Conf.Api.FeeGranterAddress)
+		if err != nil {
+			return clientCtx, err
+		}
+		clientCtx = clientCtx.WithFeeGranterAddress(granter)
+	}
+
 	return clientCtx, nil
 }
 
 func SignTx(clientCtx client.Context, msgs []sdk.Msg, gas uint64) ([]byte, error) {
+	signingLock.Lock()
+	defer signingLock.Unlock()
+
+	txBytes, _, err := signTx(clientCtx, msgs, gas, 0, 0)
+	return txBytes, err
+}
+
+// Signs the TX with the account's next sequence plus the offset and returns the sequence used.
+// The TX can't be included in a block after the timeout height (0 for no timeout). The caller must hold signingLock.
+func signTx(clientCtx client.Context, msgs []sdk.Msg, gas uint64, timeoutHeight uint64, sequenceOffset uint64) ([]byte, uint64, error) {
 	txf := BuildTxFactory(clientCtx, gas)
 	txf, txfErr := PrepareFactory(clientCtx, clientCtx.GetFromName(), txf)
 	if txfErr != nil {
-		return nil, txfErr
+		return nil, 0, txfErr
 	}
+	txf = nextSequence(clientCtx, txf)
+	txf = txf.WithSequence(txf.Sequence() + sequenceOffset).WithTimeoutHeight(timeoutHeight)
 
 	txBuilder, err := tx.BuildUnsignedTx(txf, msgs...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	txBuilder.SetFeeGranter(clientCtx.GetFeeGranterAddress())
 
 	err = tx.Sign(txf, clientCtx.GetFromName(), txBuilder, true)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return clientCtx.TxConfig.TxEncoder()(txBuilder.GetTx())
+	txBytes, err := clientCtx.TxConfig.TxEncoder()(txBuilder.GetTx())
+	return txBytes, txf.Sequence(), err
 }
 
-func SignSubmitTx(clientCtx client.Context, msgs []sdk.Msg, gas uint64) (*sdk.TxResponse, error) {
-	txBytes, err := SignTx(clientCtx, msgs, gas)
-	if err != nil {
-		return nil, err
-	}
-	return clientCtx.BroadcastTxSync(txBytes)
-}
+// Signs and broadcasts the TX. The sequence stays reserved once the TX is accepted into the mempool,
+// so other hot wallet TXs signed before it is committed use the following sequences.
+// Returns ErrSequencesHeld without broadcasting while a Zenith bundle counts on the account's next sequence.
+func signBroadcastTx(clientCtx client.Context, msgs []sdk.Msg, gas uint64, timeoutHeight uint64) (*sdk.TxResponse, []byte, error) {
+	signingLock.Lock()
+	defer signingLock.Unlock()
 
-func SubmitTxAwaitResponse(clientCtx client.Context, msgs []sdk.Msg, gas uint64) (*txTypes.GetTxResponse, error) {
-	txf := BuildTxFactory(clientCtx, gas)
-	txf, txfErr := PrepareFactory(clientCtx, clientCtx.GetFromName(), txf)
-	if txfErr != nil {
-		return nil, txfErr
+	if sequencesHeld(clientCtx.GetFromAddress().String()) {
+		return nil, nil, ErrSequencesHeld
 	}
 
-	txBuilder, err := tx.BuildUnsignedTx(txf, msgs...)
+	txBytes, sequence, err := signTx(clientCtx, msgs, gas, timeoutHeight, 0)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	txBuilder.SetFeeGranter(clientCtx.GetFeeGranterAddress())
-
-	err = tx.Sign(txf, clientCtx.GetFromName(), txBuilder, true)
-	if err != nil {
-		return nil, err
+	resp, err := clientCtx.BroadcastTxSync(txBytes)
+	if err == nil && resp != nil && resp.Code == 0 {
+		reserveSequence(clientCtx, sequence)
 	}
+	return resp, txBytes, err
+}
 
-	txBytes, err := clientCtx.TxConfig.TxEncoder()(txBuilder.GetTx())
-	if err != nil {
-		return nil, err
-	}
+func SignSubmitTx(clientCtx client.Context, msgs []sdk.Msg, gas uint64) (*sdk.TxResponse, error) {
+	resp, _, err := signBroadcastTx(clientCtx, msgs, gas, 0)
+	return resp, err
+}
 
-	resp, err := clientCtx.BroadcastTxSync(txBytes)
+// Same as SignSubmitTx, but also returns the signed TX
+func SignSubmitTxBytes(clientCtx client.Context, msgs []sdk.Msg, gas uint64) (*sdk.TxResponse, []byte, error) {
+	return signBroadcastTx(clientCtx, msgs, gas, 0)
+}
+
+// Same as SignSubmitTx, but the TX can't be included in a block after the timeout height.
+// Once the chain passes the timeout height, a TX that wasn't found on chain never will be.
+func SignSubmitTxWithTimeout(clientCtx client.Context, msgs []sdk.Msg, gas uint64, timeoutHeight uint64) (*sdk.TxResponse, error) {
+	resp, _, err := signBroadcastTx(clientCtx, msgs, gas, timeoutHeight)
+	return resp, err
+}
+
+func SubmitTxAwaitResponse(clientCtx client.Context, msgs []sdk.Msg, gas uint64) (*txTypes.GetTxResponse, error) {
+	resp, _, err := signBroadcastTx(clientCtx, msgs, gas, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -276,7 +308,7 @@ func PrepareFactory(clientCtx client.Context, keyName string, txf tx.Factory) (t
 }
 
 func BuildTxFactory(clientContext client.Context, gas uint64) tx.Factory {
-	gasPrices := "0.005uosmo"
+	gasPrices := "0.005" + FeeDenom
 	txf := newFactoryCLI(clientContext, gasPrices, gas)
 	return txf
 }
@@ -0,0 +1,83 @@
+package osmosis
+
+import (
+	"sort"
+	"strconv"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	abci "github.com/tendermint/tendermint/abci/types"
+)
+
+// Attribute newer SDK versions add to every event emitted by a message
+const attributeKeyMsgIndex = "msg_index"
+
+// Rebuilds the per message logs from the TX's flat ABCI events, for nodes and indexers that don't return logs.
+// If the events have 'msg_index' attributes they are grouped by them. Otherwise each message's events start with
+// the 'message' event holding the message's action; events before the first action are the ante handler's (e.g. fees).
+// Like in the logs, events of the same type are merged within each message.
+func MessageLogsFromEvents(events []abci.Event) sdk.ABCIMessageLogs {
+	msgEvents := map[int][]abci.Event{}
+	if hasMsgIndex(events) {
+		for _, evt := range events {
+			msgIndex, ok := getMsgIndex(evt)
+			if ok {
+				msgEvents[msgIndex] = append(msgEvents[msgIndex], evt)
+			}
+		}
+	} else {
+		msgIndex := -1
+		for _, evt := range events {
+			if isActionEvent(evt) {
+				msgIndex++
+			}
+			if msgIndex >= 0 {
+				msgEvents[msgIndex] = append(msgEvents[msgIndex], evt)
+			}
+		}
+	}
+
+	msgIndexes := []int{}
+	for msgIndex := range msgEvents {
+		msgIndexes = append(msgIndexes, msgIndex)
+	}
+	sort.Ints(msgIndexes)
+
+	logs := sdk.ABCIMessageLogs{}
+	for _, msgIndex := range msgIndexes {
+		logs = append(logs, sdk.ABCIMessageLog{MsgIndex: uint32(msgIndex), Events: sdk.StringifyEvents(msgEvents[msgIndex])})
+	}
+	return logs
+}
+
+func hasMsgIndex(events []abci.Event) bool {
+	for _, evt := range events {
+		if _, ok := getMsgIndex(evt); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func getMsgIndex(evt abci.Event) (int, bool) {
+	for _, attr := range evt.Attributes {
+		if string(attr.Key) == attributeKeyMsgIndex {
+			msgIndex, err := strconv.Atoi(string(attr.Value))
+			return msgIndex, err == nil
+		}
+	}
+	return 0, false
+}
+
+// The 'message' event the SDK emits before running each message
+func isActionEvent(evt abci.Event) bool {
+	if evt.Type != sdk.EventTypeMessage {
+		return false
+	}
+
+	for _, attr := range evt.Attributes {
+		if string(attr.Key) == sdk.AttributeKeyAction {
+			return true
+		}
+	}
+	return false
+}
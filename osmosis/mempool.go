@@ -0,0 +1,64 @@
+package osmosis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DefiantLabs/RedpointSwap/simulator"
+	"github.com/cosmos/cosmos-sdk/client"
+	gammTypes "github.com/osmosis-labs/osmosis/v13/x/gamm/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// Most pending TXs read from a node's mempool per poll
+const maxUnconfirmedTxs = 1000
+
+// A MsgSwapExactAmountIn waiting in the mempool
+type PendingSwap struct {
+	TxHash   string
+	MsgIndex int //Index of the swap message in the TX
+	Sender   string
+	Swap     *simulator.SimulatedSwap
+}
+
+// Decodes every MsgSwapExactAmountIn in the node's mempool (the node is the client's RPC endpoint).
+// TXs that can't be decoded with our codec are skipped.
+func GetPendingSwaps(queryClient client.Context) ([]PendingSwap, error) {
+	limit := maxUnconfirmedTxs
+	resp, err := queryClient.Client.UnconfirmedTxs(context.Background(), &limit)
+	if err != nil {
+		return nil, err
+	}
+
+	txDecoder := queryClient.TxConfig.TxDecoder()
+	pendingSwaps := []PendingSwap{}
+	for _, txBytes := range resp.Txs {
+		tx, err := txDecoder(txBytes)
+		if err != nil {
+			continue
+		}
+
+		hash := fmt.Sprintf("%X", tmtypes.Tx(txBytes).Hash())
+		for i, msg := range tx.GetMsgs() {
+			swapMsg, ok := msg.(*gammTypes.MsgSwapExactAmountIn)
+			if !ok || len(swapMsg.Routes) == 0 {
+				continue
+			}
+
+			routes := gammTypes.SwapAmountInRoutes(swapMsg.Routes)
+			pendingSwaps = append(pendingSwaps, PendingSwap{
+				TxHash:   hash,
+				MsgIndex: i,
+				Sender:   swapMsg.Sender,
+				Swap: &simulator.SimulatedSwap{
+					TokenIn:           swapMsg.TokenIn,
+					TokenOutMinAmount: swapMsg.TokenOutMinAmount,
+					Routes:            routes,
+					TokenOutDenom:     routes[len(routes)-1].TokenOutDenom,
+				},
+			})
+		}
+	}
+
+	return pendingSwaps, nil
+}
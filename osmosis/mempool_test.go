@@ -0,0 +1,104 @@
+package osmosis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	bankTypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	gammTypes "github.com/osmosis-labs/osmosis/v13/x/gamm/types"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// RPC client whose mempool holds the given TXs. Other RPC calls aren't implemented.
+type mempoolRpcClient struct {
+	rpcclient.Client
+	txs []tmtypes.Tx
+}
+
+func (c mempoolRpcClient) UnconfirmedTxs(_ context.Context, _ *int) (*ctypes.ResultUnconfirmedTxs, error) {
+	return &ctypes.ResultUnconfirmedTxs{Count: len(c.txs), Total: len(c.txs), Txs: c.txs}, nil
+}
+
+func encodeTx(t *testing.T, cdc Codec, msgs ...sdk.Msg) tmtypes.Tx {
+	txBuilder := cdc.TxConfig.NewTxBuilder()
+	if err := txBuilder.SetMsgs(msgs...); err != nil {
+		t.Fatal(err)
+	}
+	txBytes, err := cdc.TxConfig.TxEncoder()(txBuilder.GetTx())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return txBytes
+}
+
+func TestGetPendingSwaps(t *testing.T) {
+	cdc := MakeCodec()
+	sender := "osmo1cyyzpxplxdzkeea7kwsydadg87357qnahakaks"
+	twoHops := &gammTypes.MsgSwapExactAmountIn{
+		Sender:            sender,
+		Routes:            []gammTypes.SwapAmountInRoute{{PoolId: 1, TokenOutDenom: "uatom"}, {PoolId: 3, TokenOutDenom: "uusdc"}},
+		TokenIn:           sdk.NewCoin("uosmo", sdk.NewInt(1000000)),
+		TokenOutMinAmount: sdk.NewInt(400000),
+	}
+	oneHop := &gammTypes.MsgSwapExactAmountIn{
+		Sender:            sender,
+		Routes:            []gammTypes.SwapAmountInRoute{{PoolId: 2, TokenOutDenom: "uosmo"}},
+		TokenIn:           sdk.NewCoin("uatom", sdk.NewInt(5000)),
+		TokenOutMinAmount: sdk.NewInt(1),
+	}
+	noRoutes := &gammTypes.MsgSwapExactAmountIn{Sender: sender, TokenIn: sdk.NewCoin("uosmo", sdk.NewInt(1000)), TokenOutMinAmount: sdk.NewInt(1)}
+	send := &bankTypes.MsgSend{FromAddress: sender, ToAddress: sender, Amount: sdk.NewCoins(sdk.NewCoin("uosmo", sdk.NewInt(1)))}
+
+	type expectedSwap struct {
+		msgIndex      int
+		tokenIn       string
+		tokenOutDenom string
+		poolIds       string
+	}
+
+	cases := []struct {
+		name     string
+		txs      []tmtypes.Tx
+		expected []expectedSwap
+	}{
+		{"empty mempool", nil, []expectedSwap{}},
+		{"one swap", []tmtypes.Tx{encodeTx(t, cdc, twoHops)}, []expectedSwap{{0, "1000000uosmo", "uusdc", "[1 3]"}}},
+		{"swaps after other messages", []tmtypes.Tx{encodeTx(t, cdc, send, twoHops, oneHop)}, []expectedSwap{{1, "1000000uosmo", "uusdc", "[1 3]"}, {2, "5000uatom", "uosmo", "[2]"}}},
+		{"no swaps", []tmtypes.Tx{encodeTx(t, cdc, send)}, []expectedSwap{}},
+		{"swap without routes", []tmtypes.Tx{encodeTx(t, cdc, noRoutes)}, []expectedSwap{}},
+		{"undecodable TX", []tmtypes.Tx{[]byte("not a tx"), encodeTx(t, cdc, oneHop)}, []expectedSwap{{0, "5000uatom", "uosmo", "[2]"}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			queryClient := client.Context{}.WithClient(mempoolRpcClient{txs: c.txs}).WithTxConfig(cdc.TxConfig)
+			pendingSwaps, err := GetPendingSwaps(queryClient)
+			if err != nil {
+				t.Fatal(err)
+			} else if len(pendingSwaps) != len(c.expected) {
+				t.Fatalf("got %d pending swaps, expected %d", len(pendingSwaps), len(c.expected))
+			}
+
+			for i, expected := range c.expected {
+				pendingSwap := pendingSwaps[i]
+				if pendingSwap.MsgIndex != expected.msgIndex || pendingSwap.Sender != sender {
+					t.Errorf("pending swap %d is message %d from %s", i, pendingSwap.MsgIndex, pendingSwap.Sender)
+				}
+				if pendingSwap.Swap.TokenIn.String() != expected.tokenIn || pendingSwap.Swap.TokenOutDenom != expected.tokenOutDenom {
+					t.Errorf("pending swap %d trades %s for %s", i, pendingSwap.Swap.TokenIn, pendingSwap.Swap.TokenOutDenom)
+				}
+				if pools := fmt.Sprint(pendingSwap.Swap.PoolIds()); pools != expected.poolIds {
+					t.Errorf("pending swap %d trades through pools %s, expected %s", i, pools, expected.poolIds)
+				}
+				if pendingSwap.TxHash == "" {
+					t.Errorf("pending swap %d has no TX hash", i)
+				}
+			}
+		})
+	}
+}
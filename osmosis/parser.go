@@ -2,14 +2,15 @@ package osmosis
 
 import (
 	"fmt"
+	"strconv"
 
-	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/cosmos/cosmos-sdk/codec"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
 	"github.com/cosmos/cosmos-sdk/x/authz"
 	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	ibcTransferTypes "github.com/cosmos/ibc-go/v3/modules/apps/transfer/types"
 	gammTypes "github.com/osmosis-labs/osmosis/v13/x/gamm/types"
-	"go.uber.org/zap"
 )
 
 func convertSdkResp(currTx *txTypes.Tx, currTxResp *sdk.TxResponse) (*MergedTx, error) {
@@ -20,14 +21,20 @@ func convertSdkResp(currTx *txTypes.Tx, currTxResp *sdk.TxResponse) (*MergedTx,
 	var currMessages []sdk.Msg
 	var currLogMsgs []LogMessage
 
+	//Some nodes and indexers only return the flat ABCI events
+	msgLogs := currTxResp.Logs
+	if len(msgLogs) == 0 {
+		msgLogs = MessageLogsFromEvents(currTxResp.Events)
+	}
+
 	// Get the Messages and Message Logs
 	for msgIdx := range currTx.Body.Messages {
 		currMsg := currTx.Body.Messages[msgIdx].GetCachedValue()
 		if currMsg != nil {
 			msg := currMsg.(sdk.Msg)
 			currMessages = append(currMessages, msg)
-			if len(currTxResp.Logs) >= msgIdx+1 {
-				msgEvents := currTxResp.Logs[msgIdx].Events
+			if len(msgLogs) >= msgIdx+1 {
+				msgEvents := msgLogs[msgIdx].Events
 				currTxLog := LogMessage{
 					MessageIndex: msgIdx,
 					Events:       toEvents(msgEvents),
@@ -87,6 +94,15 @@ type Swap struct {
 	TokenIn  sdk.Coin
 	TokenOut sdk.Coin
 	Address  string
+	Hops     []SwapHop //Every pool the swap traded through, in order
+}
+
+// The trade through a single pool of a swap's route
+type SwapHop struct {
+	PoolId         uint64
+	TokenIn        sdk.Coin
+	TokenOut       sdk.Coin
+	EffectivePrice sdk.Dec //Amount of token out received per token in (after swap fees)
 }
 
 type Send struct {
@@ -95,97 +111,112 @@ type Send struct {
 	Receiver string
 }
 
+// Liquidity added to a gamm pool with MsgJoinPool
+type PoolJoin struct {
+	Address   string
+	PoolId    uint64
+	TokensIn  sdk.Coins
+	SharesOut sdk.Int
+}
+
+// Liquidity removed from a gamm pool with MsgExitPool
+type PoolExit struct {
+	Address   string
+	PoolId    uint64
+	SharesIn  sdk.Int
+	TokensOut sdk.Coins
+}
+
+// Tokens sent to another chain with an IBC MsgTransfer
+type Transfer struct {
+	Token         sdk.Coin
+	Sender        string
+	Receiver      string //Address on the destination chain
+	SourceChannel string
+}
+
 type OsmosisTx struct {
 	IsSuccessfulTx bool
-	FeePayer       string
+	FeePayer       string //The fee granter if the TX used one, otherwise the first signer
+	FeeGranter     string
 	Fees           sdk.Coins
 	Swaps          []Swap
-	Sends          []Send
+	Sends          []Send //One per coin sent, so a MsgSend or MsgMultiSend with several coins produces several sends
+	PoolJoins      []PoolJoin
+	PoolExits      []PoolExit
+	Transfers      []Transfer
 	Hash           string
+	Height         int64      //Height of the block the TX was committed in
+	Failure        *TxFailure //Why the TX failed, nil if it succeeded
 }
 
 // Parser adapted from Defiant Labs' Sycamore tax app, app.sycamore.tax, github.com/DefiantLabs/cosmos-tax-cli
-// Parses and returns token in, token out, fees, and addresses for 'MsgSwapExactAmountIn' and other types.
-func ParseRedpointSwaps(txResponse *txTypes.GetTxResponse, txHash string) OsmosisTx {
+// Parses and returns token in, token out, fees, and addresses for 'MsgSwapExactAmountIn', 'MsgSwapExactAmountOut' and other types.
+// The codec must have the TX's message types registered (see MakeCodec). Nothing is queried, so any GetTx response can be parsed.
+func ParseRedpointSwaps(txResponse *txTypes.GetTxResponse, cdc codec.Codec) OsmosisTx {
+	txHash := txResponse.TxResponse.TxHash
 	swapTx := OsmosisTx{
-		Swaps: []Swap{},
-		Sends: []Send{},
-		Hash:  txHash,
+		Swaps:  []Swap{},
+		Sends:  []Send{},
+		Hash:   txHash,
+		Height: txResponse.TxResponse.Height,
 	}
 
-	//Chain tx and query client
-	txClient, err := GetOsmosisTxClient(config.Conf.Api.ChainID, config.Conf.GetApiRpcSearchTxEndpoint(),
-		config.Conf.Api.KeyringHomeDir, config.Conf.Api.KeyringBackend, config.Conf.Api.HotWalletKey)
+	err := txResponse.Tx.UnpackInterfaces(cdc)
 	if err != nil {
-		config.Logger.Fatal("GetOsmosisTxClient", zap.Error(err))
+		fmt.Printf("Error unpacking messages for TX %s. Error: %s\n", txHash, err.Error())
 		return swapTx
 	}
 
-	txResponse.Tx.UnpackInterfaces(txClient.Codec)
-
 	if txResponse.TxResponse.Code != 0 {
+		swapTx.Failure = ParseTxFailure(txResponse.TxResponse)
 		return swapTx
 	}
 
 	swapTx.FeePayer = txResponse.Tx.FeePayer().String()
 	swapTx.Fees = txResponse.Tx.GetFee()
 
+	//With x/feegrant, the fees are deducted from the granter's account instead of the signer's
+	if granter := txResponse.Tx.FeeGranter(); !granter.Empty() {
+		swapTx.FeeGranter = granter.String()
+		swapTx.FeePayer = swapTx.FeeGranter
+	}
+
 	mergedTx, err := convertSdkResp(txResponse.Tx, txResponse.TxResponse)
 	if err != nil {
 		fmt.Printf("Error parsing SDK response for TX %s. Error: %s\n", txHash, err.Error())
 		return swapTx
 	}
 
+	//A message we can't parse is skipped, the rest of the TX is still parsed
 	for messageIndex, msg := range mergedTx.Tx.Body.Messages {
 		messageLog := GetMessageLogForIndex(mergedTx.TxResponse.Log, messageIndex)
 		msgType := txResponse.Tx.Body.Messages[messageIndex].TypeUrl
 
-		switch v := msg.(type) {
-		case *gammTypes.MsgSwapExactAmountIn:
-			msgSwap := msg.(*gammTypes.MsgSwapExactAmountIn)
-			swap, err := ParseMsgSwapExactAmountIn(msgSwap, messageLog, msgType)
-			if err != nil {
-				fmt.Printf("Error for TX with hash %s during ParseMsgSwapExactAmountIn: %s\n", txHash, err.Error())
-				return swapTx
-			}
-			swapTx.Swaps = append(swapTx.Swaps, swap)
-		case *bank.MsgSend:
-			//In the Redpoint backend, there are two reasons one of the TXs would contain a MsgSend.
-			// 1) The app makes revenue through arbitrage; it then sends a large portion of this revenue to the user
-			// 2) The app can get guaranteed block placement through Mekatek's Zenith service, which requires paying small fees
-			// This parses the amount, sender, and receiver. It doesn't care which situation (1) or (2) happened.
-			msgSend := msg.(*bank.MsgSend)
-			if len(msgSend.Amount) != 1 {
-				fmt.Printf("Error for TX with hash %s; unexpected MsgSend, %d tokens sent\n", txHash, len(msgSend.Amount))
-				return swapTx
-			}
-			send := Send{
-				Sender:   msgSend.FromAddress,
-				Receiver: msgSend.ToAddress,
-				Token:    msgSend.Amount[0],
-			}
-			swapTx.Sends = append(swapTx.Sends, send)
-		case *authz.MsgExec: //TODO: verify the log messages produced when you do a MsgExec w/ an inner swap
-			msgExec := msg.(*authz.MsgExec)
-			fmt.Printf("placeholder %+v", msgExec)
-			msgs, err := msgExec.GetMessages()
+		//Number of 'token_swapped' hops in the message log that were already matched to a swap
+		parsedHops := 0
+
+		msgExec, ok := msg.(*authz.MsgExec)
+		if !ok {
+			err = parseMessage(&swapTx, msg, messageLog, msgType, &parsedHops)
 			if err != nil {
-				fmt.Printf("Error for TX with hash %s (message index: %d) during msgExec.GetMessages(): %s\n", txHash, messageIndex, err.Error())
-				return swapTx
+				fmt.Printf("Error for TX with hash %s (message index: %d): %s\n", txHash, messageIndex, err.Error())
 			}
+			continue
+		}
 
-			for _, msg := range msgs {
-				msgSwap := msg.(*gammTypes.MsgSwapExactAmountIn)
-				swap, err := ParseMsgSwapExactAmountIn(msgSwap, messageLog, msgType)
-				if err != nil {
-					fmt.Printf("Error for TX with hash %s during ParseMsgSwapExactAmountIn: %s\n", txHash, err.Error())
-					return swapTx
-				}
-				swapTx.Swaps = append(swapTx.Swaps, swap)
-				fmt.Printf("Parsed MsgExec (grantee swap). Swap address: %s, token in: %s, token out: %s\n", swap.Address, swap.TokenIn, swap.TokenOut)
+		//The inner messages of a MsgExec share the MsgExec's message log
+		msgs, err := msgExec.GetMessages()
+		if err != nil {
+			fmt.Printf("Error for TX with hash %s (message index: %d) during msgExec.GetMessages(): %s\n", txHash, messageIndex, err.Error())
+			continue
+		}
+
+		for innerIndex, innerMsg := range msgs {
+			err = parseMessage(&swapTx, innerMsg, messageLog, msgType, &parsedHops)
+			if err != nil {
+				fmt.Printf("Error for TX with hash %s (message index: %d, MsgExec inner message index: %d): %s\n", txHash, messageIndex, innerIndex, err.Error())
 			}
-		default:
-			fmt.Printf("Unknown type '%s', msg String(): %s\n", v, msg.String())
 		}
 	}
 
@@ -193,8 +224,126 @@ func ParseRedpointSwaps(txResponse *txTypes.GetTxResponse, txHash string) Osmosi
 	return swapTx
 }
 
-// Parse an Osmosis gamm MsgSwapExactAmountIn, using the message logs to determine amount received
-func ParseMsgSwapExactAmountIn(msg *gammTypes.MsgSwapExactAmountIn, messageLog *LogMessage, msgType string) (Swap, error) {
+// Parses a single message (not a MsgExec) into the TX. msgType is the type URL the message log's action is checked against.
+// Swaps take their hops from the message log starting at parsedHops, which is advanced past them.
+// Unknown message types are logged and ignored.
+func parseMessage(swapTx *OsmosisTx, msg sdk.Msg, messageLog *LogMessage, msgType string, parsedHops *int) error {
+	switch v := msg.(type) {
+	case *gammTypes.MsgSwapExactAmountIn:
+		swap, err := ParseMsgSwapExactAmountIn(v, messageLog, msgType, *parsedHops)
+		if err != nil {
+			return fmt.Errorf("ParseMsgSwapExactAmountIn: %s", err.Error())
+		}
+		*parsedHops += len(swap.Hops)
+		swapTx.Swaps = append(swapTx.Swaps, swap)
+	case *gammTypes.MsgSwapExactAmountOut:
+		swap, err := ParseMsgSwapExactAmountOut(v, messageLog, msgType, *parsedHops)
+		if err != nil {
+			return fmt.Errorf("ParseMsgSwapExactAmountOut: %s", err.Error())
+		}
+		*parsedHops += len(swap.Hops)
+		swapTx.Swaps = append(swapTx.Swaps, swap)
+	case *bank.MsgSend:
+		//In the Redpoint backend, there are two reasons one of the TXs would contain a MsgSend.
+		// 1) The app makes revenue through arbitrage; it then sends a large portion of this revenue to the user
+		// 2) The app can get guaranteed block placement through Mekatek's Zenith service, which requires paying small fees
+		// This parses the amount, sender, and receiver. It doesn't care which situation (1) or (2) happened.
+		for _, token := range v.Amount {
+			swapTx.Sends = append(swapTx.Sends, Send{Sender: v.FromAddress, Receiver: v.ToAddress, Token: token})
+		}
+	case *bank.MsgMultiSend:
+		//Inputs and outputs aren't paired, so the sender is only known if there is a single input
+		sender := ""
+		if len(v.Inputs) == 1 {
+			sender = v.Inputs[0].Address
+		}
+		for _, output := range v.Outputs {
+			for _, token := range output.Coins {
+				swapTx.Sends = append(swapTx.Sends, Send{Sender: sender, Receiver: output.Address, Token: token})
+			}
+		}
+	case *gammTypes.MsgJoinPool:
+		join, err := ParseMsgJoinPool(v, messageLog, msgType)
+		if err != nil {
+			return fmt.Errorf("ParseMsgJoinPool: %s", err.Error())
+		}
+		swapTx.PoolJoins = append(swapTx.PoolJoins, join)
+	case *gammTypes.MsgExitPool:
+		exit, err := ParseMsgExitPool(v, messageLog, msgType)
+		if err != nil {
+			return fmt.Errorf("ParseMsgExitPool: %s", err.Error())
+		}
+		swapTx.PoolExits = append(swapTx.PoolExits, exit)
+	case *ibcTransferTypes.MsgTransfer:
+		swapTx.Transfers = append(swapTx.Transfers, Transfer{
+			Token:         v.Token,
+			Sender:        v.Sender,
+			Receiver:      v.Receiver,
+			SourceChannel: v.SourceChannel,
+		})
+	default:
+		fmt.Printf("Unknown type '%T', msg String(): %s\n", v, msg.String())
+	}
+
+	return nil
+}
+
+// Parse an Osmosis gamm MsgJoinPool, using the message logs to determine the tokens added to the pool
+func ParseMsgJoinPool(msg *gammTypes.MsgJoinPool, messageLog *LogMessage, msgType string) (PoolJoin, error) {
+	join := PoolJoin{Address: msg.Sender, PoolId: msg.PoolId, SharesOut: msg.ShareOutAmount}
+	tokensIn, err := parsePoolEventTokens(gammTypes.TypeEvtPoolJoined, gammTypes.AttributeKeyTokensIn, messageLog, msgType)
+	if err != nil {
+		return join, err
+	}
+
+	join.TokensIn = tokensIn
+	return join, nil
+}
+
+// Parse an Osmosis gamm MsgExitPool, using the message logs to determine the tokens removed from the pool
+func ParseMsgExitPool(msg *gammTypes.MsgExitPool, messageLog *LogMessage, msgType string) (PoolExit, error) {
+	exit := PoolExit{Address: msg.Sender, PoolId: msg.PoolId, SharesIn: msg.ShareInAmount}
+	tokensOut, err := parsePoolEventTokens(gammTypes.TypeEvtPoolExited, gammTypes.AttributeKeyTokensOut, messageLog, msgType)
+	if err != nil {
+		return exit, err
+	}
+
+	exit.TokensOut = tokensOut
+	return exit, nil
+}
+
+func parsePoolEventTokens(eventType string, attributeKey string, messageLog *LogMessage, msgType string) (sdk.Coins, error) {
+	if !IsMessageActionEquals(msgType, messageLog) {
+		return nil, fmt.Errorf("error 'IsMessageActionEquals'. Message type: %s, message log: %+v", msgType, messageLog)
+	}
+
+	evt := GetEventWithType(eventType, messageLog)
+	if evt == nil {
+		return nil, fmt.Errorf("error getting event type '%s', message log: %+v", eventType, messageLog)
+	}
+
+	tokens, err := sdk.ParseCoinsNormalized(GetValueForAttribute(attributeKey, evt))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s. Event: %+v, Err: %s", attributeKey, evt, err.Error())
+	}
+	return tokens, nil
+}
+
+// Parse an Osmosis gamm MsgSwapExactAmountIn, using the message logs to determine amount received.
+// The swap's hops start at hopOffset in the message log's 'token_swapped' events.
+func ParseMsgSwapExactAmountIn(msg *gammTypes.MsgSwapExactAmountIn, messageLog *LogMessage, msgType string, hopOffset int) (Swap, error) {
+	return parseTokensSwapped(msg.Sender, len(msg.Routes), messageLog, msgType, hopOffset)
+}
+
+// Parse an Osmosis gamm MsgSwapExactAmountOut, using the message logs to determine amount sent.
+// The swap's hops start at hopOffset in the message log's 'token_swapped' events.
+func ParseMsgSwapExactAmountOut(msg *gammTypes.MsgSwapExactAmountOut, messageLog *LogMessage, msgType string, hopOffset int) (Swap, error) {
+	return parseTokensSwapped(msg.Sender, len(msg.Routes), messageLog, msgType, hopOffset)
+}
+
+// Both swap types emit the same 'token_swapped' events, one per pool traded through.
+// Events of the same type are merged in the message log, so every hop of every swap in the message is in one event.
+func parseTokensSwapped(sender string, numHops int, messageLog *LogMessage, msgType string, hopOffset int) (Swap, error) {
 	var swap Swap
 	// Confirm that the action listed in the message log matches the Message type
 	validLog := IsMessageActionEquals(msgType, messageLog)
@@ -208,30 +357,74 @@ func ParseMsgSwapExactAmountIn(msg *gammTypes.MsgSwapExactAmountIn, messageLog *
 		return swap, fmt.Errorf("error getting event type '%s', message log: %+v", gammTypes.TypeEvtTokenSwapped, messageLog)
 	}
 
-	// Address of whoever initiated the swap. Will be both sender/receiver.
-	senderReceiver := GetValueForAttribute("sender", tokensSwappedEvt)
-	if senderReceiver == "" {
-		return swap, fmt.Errorf("error getting sender from token swapped event: %+v", tokensSwappedEvt)
+	hops, err := parseSwapHops(tokensSwappedEvt)
+	if err != nil {
+		return swap, err
+	} else if numHops == 0 || len(hops) < hopOffset+numHops {
+		return swap, fmt.Errorf("expected %d hops after hop %d, but token swapped event has %d hops: %+v", numHops, hopOffset, len(hops), tokensSwappedEvt)
 	}
 
-	// This gets the first token swapped in (if there are multiple pools we do not care about intermediates)
-	tokenInStr := GetValueForAttribute(gammTypes.AttributeKeyTokensIn, tokensSwappedEvt)
-	tokenIn, err := sdk.ParseCoinNormalized(tokenInStr)
-	if err != nil {
-		return swap, fmt.Errorf("error parsing coins in. Event: %+v, Err %s: ", tokensSwappedEvt, err.Error())
+	swap.Hops = hops[hopOffset : hopOffset+numHops]
+	swap.TokenIn = swap.Hops[0].TokenIn
+	swap.TokenOut = swap.Hops[numHops-1].TokenOut
+	swap.Address = sender
+	return swap, nil
+}
+
+// Every hop in a 'token_swapped' event. Each hop's attributes start with the module name (then sender, pool, tokens in and out).
+func parseSwapHops(evt *LogMessageEvent) ([]SwapHop, error) {
+	type hopAttributes struct {
+		sender, poolId, tokensIn, tokensOut string
 	}
 
-	// This gets the last token swapped out (if there are multiple pools we do not care about intermediates)
-	tokenOutStr := GetLastValueForAttribute(gammTypes.AttributeKeyTokensOut, tokensSwappedEvt)
-	tokenOut, err := sdk.ParseCoinNormalized(tokenOutStr)
-	if err != nil {
-		return swap, fmt.Errorf("error parsing coins out. err %s: ", err.Error())
+	hopAttrs := []*hopAttributes{}
+	for _, attr := range evt.Attributes {
+		if attr.Key == sdk.AttributeKeyModule || len(hopAttrs) == 0 {
+			hopAttrs = append(hopAttrs, &hopAttributes{})
+		}
+
+		current := hopAttrs[len(hopAttrs)-1]
+		switch attr.Key {
+		case sdk.AttributeKeySender:
+			current.sender = attr.Value
+		case gammTypes.AttributeKeyPoolId:
+			current.poolId = attr.Value
+		case gammTypes.AttributeKeyTokensIn:
+			current.tokensIn = attr.Value
+		case gammTypes.AttributeKeyTokensOut:
+			current.tokensOut = attr.Value
+		}
 	}
 
-	swap.TokenIn = tokenIn
-	swap.TokenOut = tokenOut
-	swap.Address = msg.Sender
-	return swap, nil
+	hops := []SwapHop{}
+	for _, attrs := range hopAttrs {
+		if attrs.sender == "" {
+			return nil, fmt.Errorf("error getting sender from token swapped event: %+v", evt)
+		}
+
+		poolId, err := strconv.ParseUint(attrs.poolId, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing pool ID. Event: %+v, Err: %s", evt, err.Error())
+		}
+
+		tokenIn, err := sdk.ParseCoinNormalized(attrs.tokensIn)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing coins in. Event: %+v, Err %s: ", evt, err.Error())
+		}
+
+		tokenOut, err := sdk.ParseCoinNormalized(attrs.tokensOut)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing coins out. err %s: ", err.Error())
+		}
+
+		hop := SwapHop{PoolId: poolId, TokenIn: tokenIn, TokenOut: tokenOut, EffectivePrice: sdk.ZeroDec()}
+		if tokenIn.Amount.IsPositive() {
+			hop.EffectivePrice = tokenOut.Amount.ToDec().Quo(tokenIn.Amount.ToDec())
+		}
+		hops = append(hops, hop)
+	}
+
+	return hops, nil
 }
 
 func GetLastValueForAttribute(key string, evt *LogMessageEvent) string {
@@ -0,0 +1,71 @@
+package osmosis
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// Run 'go test ./osmosis -run TestParseRedpointSwaps -update' to rewrite the golden files after changing the parser
+var updateGolden = flag.Bool("update", false, "update the parser's golden files in testdata")
+
+// Each testdata/<name>.json is a GetTx response, testdata/<name>.golden.json is the OsmosisTx parsed from it
+var parserFixtures = []string{
+	"user_swap",               //Two hop MsgSwapExactAmountIn signed by the user
+	"user_swap_exact_out",     //MsgSwapExactAmountOut signed by the user
+	"authz_exec",              //Hot wallet MsgExec of the user's swap, then the hot wallet's arbitrage. Fees paid by a fee granter.
+	"zenith_bundle_arbitrage", //Hot wallet TX in a Zenith bundle: two arbitrage swaps and the auction payments
+	"profit_share_payout",     //Hot wallet MsgSend of the user's profit share in two denoms
+	"failed_swap",             //Swap that failed its minimum amount out (nonzero code, no message logs)
+	"liquidity_and_transfers", //MsgJoinPool, MsgExitPool, MsgMultiSend and an IBC MsgTransfer
+	//The same TXs from nodes that don't return logs, parsed from the flat ABCI events
+	"authz_exec_events_only",            //Events without message indexes (split on each message's action)
+	"zenith_bundle_arbitrage_msg_index", //Events with msg_index attributes
+}
+
+func TestParseRedpointSwaps(t *testing.T) {
+	cdc := MakeCodec()
+
+	for _, name := range parserFixtures {
+		t.Run(name, func(t *testing.T) {
+			txJson, err := os.ReadFile(filepath.Join("testdata", name+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			txResponse := &txTypes.GetTxResponse{}
+			err = cdc.Marshaler.UnmarshalJSON(txJson, txResponse)
+			if err != nil {
+				t.Fatalf("unmarshal %s.json: %s", name, err.Error())
+			}
+
+			parsed, err := json.MarshalIndent(ParseRedpointSwaps(txResponse, cdc.Marshaler), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed = append(parsed, '\n')
+
+			goldenFile := filepath.Join("testdata", name+".golden.json")
+			if *updateGolden {
+				err = os.WriteFile(goldenFile, parsed, 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := os.ReadFile(goldenFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(parsed, expected) {
+				t.Errorf("parsed TX does not match %s.\nGot:\n%s\nExpected:\n%s", goldenFile, parsed, expected)
+			}
+		})
+	}
+}
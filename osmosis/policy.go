@@ -0,0 +1,58 @@
+package osmosis
+
+import (
+	"fmt"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/DefiantLabs/RedpointSwap/simulator"
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// The route and asset policy from the config
+func GetPolicy() (*simulator.Policy, error) {
+	conf := config.Conf.Policy
+	policy := &simulator.Policy{
+		AllowedPools:   map[uint64]bool{},
+		DeniedPools:    map[uint64]bool{},
+		AllowedDenoms:  map[string]bool{},
+		MaxHops:        conf.MaxHops,
+		MaxPriceImpact: conf.MaxPriceImpact,
+	}
+
+	for _, poolId := range conf.AllowedPools {
+		policy.AllowedPools[poolId] = true
+	}
+	for _, poolId := range conf.DeniedPools {
+		policy.DeniedPools[poolId] = true
+	}
+	for _, denom := range conf.AllowedDenoms {
+		policy.AllowedDenoms[denom] = true
+	}
+
+	if conf.MinPoolLiquidity != "" {
+		minLiquidity, err := sdk.ParseCoinsNormalized(conf.MinPoolLiquidity)
+		if err != nil {
+			return nil, fmt.Errorf("server misconfiguration (policy MinPoolLiquidity): %s", err.Error())
+		}
+		policy.MinPoolLiquidity = minLiquidity
+	}
+
+	return policy, nil
+}
+
+// Checks the user swap and every arbitrage swap in the simulation against the configured policy.
+// Rejections wrap simulator.ErrPolicyViolation and say which swap, pool or denom broke the policy.
+func CheckPolicy(queryClient client.Context, result *simulator.SimulatedSwapResult) error {
+	policy, err := GetPolicy()
+	if err != nil {
+		return err
+	}
+
+	pools, err := GetPoolStates(queryClient, result.PoolIds())
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPoolDataUnavailable, err.Error())
+	}
+
+	return policy.CheckSimulation(pools, result)
+}
@@ -0,0 +1,163 @@
+package osmosis
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/DefiantLabs/RedpointSwap/simulator"
+	"github.com/cosmos/cosmos-sdk/client"
+	"go.uber.org/zap"
+)
+
+// Pools that haven't been looked up for this long are no longer refreshed each block
+const activePoolDuration = 10 * time.Minute
+
+// Latest known state of each pool. Key: pool ID, Value: *simulator.PoolState
+var poolCache sync.Map
+
+// Pools on routes we recently simulated or traded. Key: pool ID, Value: time.Time of the last lookup
+var activePools sync.Map
+
+var poolCacheLock sync.Mutex
+
+// Snapshot of every pool and the height it was queried at (only used when config CacheAllPools is off)
+var allPoolsLock sync.Mutex
+var allPools simulator.PoolStates
+var allPoolsHeight int64
+
+// Pool state for each of the given pools. Served from the cache when possible, otherwise queried.
+// The returned pools are copies, so callers can modify them (e.g. to apply a swap).
+func GetPoolStates(queryClient client.Context, poolIds []uint64) (simulator.PoolStates, error) {
+	pools := simulator.PoolStates{}
+	for _, poolId := range poolIds {
+		if _, ok := pools[poolId]; ok {
+			continue
+		}
+
+		activePools.Store(poolId, time.Now())
+		if val, ok := poolCache.Load(poolId); ok {
+			pools[poolId] = val.(*simulator.PoolState).Clone()
+			continue
+		}
+
+		pool, err := QueryPoolState(queryClient, poolId)
+		if err != nil {
+			return nil, err
+		}
+
+		pool.Height = LatestHeight()
+		poolCache.Store(poolId, pool)
+		pools[poolId] = pool.Clone()
+	}
+
+	return pools, nil
+}
+
+// Copies of every cached pool
+func CachedPoolStates() simulator.PoolStates {
+	pools := simulator.PoolStates{}
+	poolCache.Range(func(key, val any) bool {
+		pools[key.(uint64)] = val.(*simulator.PoolState).Clone()
+		return true
+	})
+	return pools
+}
+
+// Copies of every pool on chain. When config CacheAllPools is off, all pools are queried at most once per block
+// and pools in the cache (which may be fresher) take precedence.
+func AllPoolStates(queryClient client.Context) (simulator.PoolStates, error) {
+	if config.Conf.Api.CacheAllPools {
+		return CachedPoolStates(), nil
+	}
+
+	allPoolsLock.Lock()
+	defer allPoolsLock.Unlock()
+
+	if allPools == nil || allPoolsHeight < LatestHeight() {
+		pools, err := QueryAllPoolStates(queryClient)
+		if err != nil {
+			return nil, err
+		}
+
+		allPools = simulator.PoolStates{}
+		for _, pool := range pools {
+			pool.Height = LatestHeight()
+			allPools[pool.PoolId] = pool
+		}
+		allPoolsHeight = LatestHeight()
+	}
+
+	pools := allPools.Clone()
+	for poolId, pool := range CachedPoolStates() {
+		pools[poolId] = pool
+	}
+	return pools, nil
+}
+
+// Removes the pools from the cache so the next lookup queries them again.
+// Called when our own TXs that traded through the pools are committed.
+func InvalidatePools(poolIds []uint64) {
+	for _, poolId := range poolIds {
+		poolCache.Delete(poolId)
+	}
+}
+
+// This function is called for every new block produced on the chain.
+// Refreshes the cached state of every active pool (or every pool on chain, see config CacheAllPools) in the background,
+// so the handlers after it (e.g. Zenith bids) don't wait for the refresh. Until it is done, they see the last block's pool states.
+func PoolCacheBlockNotificationHandler(chainHeight int64, _ int64) {
+	//Refreshing can take longer than a block, skip blocks until the last refresh is done
+	if !poolCacheLock.TryLock() {
+		return
+	}
+
+	go func() {
+		defer poolCacheLock.Unlock()
+		refreshPoolCache(chainHeight)
+	}()
+}
+
+func refreshPoolCache(chainHeight int64) {
+	conf := config.Conf
+	txClientSearch, err := GetOsmosisTxClient(conf.Api.ChainID, conf.GetApiRpcSearchTxEndpoint(), conf.Api.KeyringHomeDir, conf.Api.KeyringBackend, conf.Api.HotWalletKey)
+	if err != nil {
+		config.Logger.Error("GetOsmosisTxClient", zap.Error(err))
+		return
+	}
+
+	if conf.Api.CacheAllPools {
+		pools, err := QueryAllPoolStates(txClientSearch)
+		if err != nil {
+			config.Logger.Error("Pool cache: failed to query pools", zap.Error(err), zap.Int64("height", chainHeight))
+			return
+		}
+
+		for _, pool := range pools {
+			pool.Height = chainHeight
+			poolCache.Store(pool.PoolId, pool)
+		}
+		return
+	}
+
+	activePools.Range(func(key, val any) bool {
+		poolId := key.(uint64)
+		if time.Since(val.(time.Time)) > activePoolDuration {
+			activePools.Delete(poolId)
+			poolCache.Delete(poolId)
+			return true
+		}
+
+		pool, err := QueryPoolState(txClientSearch, poolId)
+		if err != nil {
+			//Don't serve a stale pool state
+			poolCache.Delete(poolId)
+			config.Logger.Error("Pool cache: failed to query pool", zap.Error(err), zap.Uint64("pool", poolId), zap.Int64("height", chainHeight))
+			return true
+		}
+
+		pool.Height = chainHeight
+		poolCache.Store(poolId, pool)
+		return true
+	})
+}
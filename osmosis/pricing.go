@@ -0,0 +1,74 @@
+package osmosis
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// All TX fees and Zenith payments are paid in this denom
+const FeeDenom = "uosmo"
+
+// How long a spot price can be used before it is queried again
+const priceCacheDuration = 30 * time.Second
+
+// Tracks the price of each arbitrage denom in the fee denom. Key: denom, Value: *cachedPrice
+var feeDenomPrices sync.Map
+
+type cachedPrice struct {
+	price     sdk.Dec
+	queriedAt time.Time
+}
+
+// Price of one token of the given denom in the fee denom, using the denom's configured pricing pool
+func GetFeeDenomPrice(queryClient client.Context, denom string) (sdk.Dec, error) {
+	if denom == FeeDenom {
+		return sdk.OneDec(), nil
+	}
+
+	if val, ok := feeDenomPrices.Load(denom); ok {
+		cached := val.(*cachedPrice)
+		if time.Since(cached.queriedAt) < priceCacheDuration {
+			return cached.price, nil
+		}
+	}
+
+	capital, ok := config.Conf.GetArbitrageCapitalForDenom(denom)
+	if !ok || capital.PricePoolId == 0 {
+		return sdk.Dec{}, fmt.Errorf("no pricing pool configured for denom %s", denom)
+	}
+
+	price, err := QuerySpotPrice(queryClient, capital.PricePoolId, FeeDenom, denom)
+	if err != nil {
+		return sdk.Dec{}, err
+	} else if !price.IsPositive() {
+		return sdk.Dec{}, fmt.Errorf("invalid price %s for denom %s", price, denom)
+	}
+
+	feeDenomPrices.Store(denom, &cachedPrice{price: price, queriedAt: time.Now()})
+	return price, nil
+}
+
+// Value of the given amount in the fee denom (truncated)
+func ToFeeDenom(queryClient client.Context, amount sdk.Int, denom string) (sdk.Int, error) {
+	price, err := GetFeeDenomPrice(queryClient, denom)
+	if err != nil {
+		return sdk.Int{}, err
+	}
+
+	return amount.ToDec().Mul(price).TruncateInt(), nil
+}
+
+// Converts an amount of the fee denom to the given denom (rounded up, since this is used for fees)
+func FromFeeDenom(queryClient client.Context, feeAmount sdk.Int, denom string) (sdk.Int, error) {
+	price, err := GetFeeDenomPrice(queryClient, denom)
+	if err != nil {
+		return sdk.Int{}, err
+	}
+
+	return feeAmount.ToDec().Quo(price).Ceil().TruncateInt(), nil
+}
@@ -2,10 +2,18 @@ package osmosis
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/DefiantLabs/RedpointSwap/simulator"
 	"github.com/cosmos/cosmos-sdk/client"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/query"
 	bankTypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/cosmos-sdk/x/feegrant"
+	"github.com/osmosis-labs/osmosis/v13/x/gamm/pool-models/balancer"
+	"github.com/osmosis-labs/osmosis/v13/x/gamm/pool-models/stableswap"
+	gammTypes "github.com/osmosis-labs/osmosis/v13/x/gamm/types"
 )
 
 func GetAccountBalances(queryClient client.Context, address string) (map[string]sdk.Int, error) {
@@ -23,3 +31,115 @@ func GetAccountBalances(queryClient client.Context, address string) (map[string]
 
 	return balances, nil
 }
+
+// Looks up the hot wallet's balances and updates its arbitrage balances (see config GetHotWalletArbBalance)
+func RefreshHotWalletArbBalances(queryClient client.Context, hotWalletAddress string) error {
+	balances, err := GetAccountBalances(queryClient, hotWalletAddress)
+	if err != nil {
+		return err
+	}
+
+	arbBalances := map[string]sdk.Int{}
+	for _, capital := range config.Conf.GetArbitrageCapital() {
+		arbBalances[capital.Denom] = GetTokenBalance(capital.Denom, balances)
+	}
+	config.SetHotWalletArbBalances(hotWalletAddress, arbBalances)
+	return nil
+}
+
+// Returns an error if the granter has not given the grantee an x/feegrant allowance
+func HasFeeAllowance(queryClient client.Context, granter string, grantee string) error {
+	req := &feegrant.QueryAllowanceRequest{Granter: granter, Grantee: grantee}
+	querier := feegrant.NewQueryClient(queryClient)
+	_, err := querier.Allowance(context.Background(), req)
+	return err
+}
+
+// Price of one quote denom token in terms of the base denom, e.g. base uosmo and quote uatom returns how many uosmo one uatom costs
+func QuerySpotPrice(queryClient client.Context, poolId uint64, baseDenom string, quoteDenom string) (sdk.Dec, error) {
+	req := &gammTypes.QuerySpotPriceRequest{PoolId: poolId, BaseAssetDenom: baseDenom, QuoteAssetDenom: quoteDenom}
+	querier := gammTypes.NewQueryClient(queryClient)
+	resp, err := querier.SpotPrice(context.Background(), req)
+	if err != nil {
+		return sdk.Dec{}, err
+	}
+
+	return sdk.NewDecFromStr(resp.SpotPrice)
+}
+
+// Reserves and weights (or scaling factors) of the given pool. Only balancer and stableswap pools are supported.
+func QueryPoolState(queryClient client.Context, poolId uint64) (*simulator.PoolState, error) {
+	req := &gammTypes.QueryPoolRequest{PoolId: poolId}
+	querier := gammTypes.NewQueryClient(queryClient)
+	resp, err := querier.Pool(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	var pool gammTypes.PoolI
+	err = queryClient.InterfaceRegistry.UnpackAny(resp.Pool, &pool)
+	if err != nil {
+		return nil, err
+	}
+
+	return ToPoolState(pool)
+}
+
+// Converts the on-chain pool to the state the simulator needs for swap math
+func ToPoolState(pool gammTypes.PoolI) (*simulator.PoolState, error) {
+	state := &simulator.PoolState{PoolId: pool.GetId(), Assets: map[string]simulator.PoolAsset{}}
+
+	switch p := pool.(type) {
+	case *balancer.Pool:
+		state.PoolType = simulator.PoolTypeBalancer
+		state.SwapFee = p.PoolParams.SwapFee
+		for _, asset := range p.PoolAssets {
+			state.Assets[asset.Token.Denom] = simulator.PoolAsset{Reserve: asset.Token.Amount, Weight: asset.Weight}
+		}
+	case *stableswap.Pool:
+		if len(p.ScalingFactors) != len(p.PoolLiquidity) {
+			return nil, fmt.Errorf("pool %d has %d scaling factors for %d assets", p.Id, len(p.ScalingFactors), len(p.PoolLiquidity))
+		}
+		state.PoolType = simulator.PoolTypeStableswap
+		state.SwapFee = p.PoolParams.SwapFee
+		for i, coin := range p.PoolLiquidity {
+			state.Assets[coin.Denom] = simulator.PoolAsset{Reserve: coin.Amount, ScalingFactor: p.ScalingFactors[i]}
+		}
+	default:
+		return nil, fmt.Errorf("pool %d has unsupported pool type %T", pool.GetId(), pool)
+	}
+
+	return state, nil
+}
+
+// Every gamm pool on chain. Pools of unsupported types are skipped.
+func QueryAllPoolStates(queryClient client.Context) ([]*simulator.PoolState, error) {
+	pools := []*simulator.PoolState{}
+	querier := gammTypes.NewQueryClient(queryClient)
+	req := &gammTypes.QueryPoolsRequest{Pagination: &query.PageRequest{Limit: 500}}
+
+	for {
+		resp, err := querier.Pools(context.Background(), req)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, poolAny := range resp.Pools {
+			var pool gammTypes.PoolI
+			err = queryClient.InterfaceRegistry.UnpackAny(poolAny, &pool)
+			if err != nil {
+				return nil, err
+			}
+
+			state, err := ToPoolState(pool)
+			if err == nil {
+				pools = append(pools, state)
+			}
+		}
+
+		if resp.Pagination == nil || len(resp.Pagination.NextKey) == 0 {
+			return pools, nil
+		}
+		req.Pagination = &query.PageRequest{Key: resp.Pagination.NextKey, Limit: 500}
+	}
+}
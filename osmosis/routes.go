@@ -0,0 +1,309 @@
+package osmosis
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/DefiantLabs/RedpointSwap/simulator"
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"go.uber.org/zap"
+)
+
+var ErrStaleSimulation = errors.New("simulation no longer holds at current pool state")
+
+// Most arbitrage cycles we look for after a single user swap (each one costs gas)
+const maxArbitrageSwaps = 3
+
+// Brings the simulation up to date with the current pool state (if the pools changed since it was simulated).
+// Each arbitrage swap is re-sized to the current reserves, and dropped if it is no longer profitable.
+// If none are left, new routes are searched for. Returns ErrStaleSimulation if the user's swap would now fail or there is no arbitrage left.
+func RefreshSimulation(queryClient client.Context, result *simulator.SimulatedSwapResult) error {
+	userSwap := result.SimulatedUserSwap
+	if userSwap == nil {
+		return errors.New("simulation has no user swap")
+	}
+
+	pools, err := GetPoolStates(queryClient, result.PoolIds())
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPoolDataUnavailable, err.Error())
+	}
+
+	height := pools.Height()
+	if result.SimulationHeight != 0 && height != 0 && result.SimulationHeight >= height {
+		return nil
+	}
+
+	err = ApplyUserSwap(pools, userSwap)
+	if err != nil {
+		return err
+	}
+
+	arbSwaps := result.GetArbitrageSwaps()
+	profitable := resizeArbitrageSwaps(pools, arbSwaps, queryClient.GetFromAddress().String())
+	if len(profitable) > 0 {
+		result.SetArbitrageSwaps(profitable)
+		result.SimulationHeight = height
+		return nil
+	}
+
+	//The arbitrage no longer holds (or there never was any), search for new routes
+	result.SetArbitrageSwaps(nil)
+	err = FindArbitrage(queryClient, result)
+	if err != nil {
+		return err
+	} else if !result.HasArbitrageOpportunity && len(arbSwaps) > 0 {
+		return fmt.Errorf("%w: no arbitrage left", ErrStaleSimulation)
+	}
+
+	result.SimulationHeight = height
+	return nil
+}
+
+// Applies the user's swap to the pools. Returns ErrStaleSimulation if the user's signed TX would fail
+// (e.g. it can no longer meet its minimum amount out).
+func ApplyUserSwap(pools simulator.PoolStates, userSwap *simulator.SimulatedSwap) error {
+	userAmountIn, err := pools.SwapAmountIn(userSwap)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrStaleSimulation, err.Error())
+	}
+
+	//The user's signed TX fails if the minimum amount out can no longer be met
+	if !userSwap.IsExactAmountOut() && !userSwap.TokenOutMinAmount.IsNil() {
+		amountOut, err := pools.SimulateRoute(userSwap.Hops(), userAmountIn)
+		if err != nil {
+			return fmt.Errorf("%w: user swap would not meet its minimum amount out", ErrStaleSimulation)
+		}
+		amountOutInt, err := simulator.IntFromFloat(amountOut)
+		if err != nil || amountOutInt.LT(userSwap.TokenOutMinAmount) {
+			return fmt.Errorf("%w: user swap would not meet its minimum amount out", ErrStaleSimulation)
+		}
+	}
+
+	err = pools.ApplyRoute(userSwap.Hops(), userAmountIn)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrStaleSimulation, err.Error())
+	}
+	return nil
+}
+
+// Re-sizes the simulation's arbitrage swaps to the pools (which should already include the user's swap), as they execute in order.
+// Swaps that are no longer profitable are dropped. The pools are left as they will be after the arbitrage.
+// Returns ErrStaleSimulation if there is no arbitrage left.
+func ResizeArbitrage(pools simulator.PoolStates, result *simulator.SimulatedSwapResult, hotWalletAddress string) error {
+	profitable := resizeArbitrageSwaps(pools, result.GetArbitrageSwaps(), hotWalletAddress)
+	result.SetArbitrageSwaps(profitable)
+	if len(profitable) == 0 {
+		return fmt.Errorf("%w: no arbitrage left", ErrStaleSimulation)
+	}
+	return nil
+}
+
+func resizeArbitrageSwaps(pools simulator.PoolStates, arbSwaps []*simulator.ArbitrageSwap, hotWalletAddress string) []*simulator.ArbitrageSwap {
+	profitable := []*simulator.ArbitrageSwap{}
+	for _, arbSwap := range arbSwaps {
+		if refreshArbitrageSwap(pools, arbSwap, hotWalletAddress) {
+			profitable = append(profitable, arbSwap)
+		}
+	}
+	return profitable
+}
+
+// Re-sizes an exact amount in arbitrage swap to the pools, or checks an exact amount out arbitrage swap still holds.
+// If the swap is still profitable, the pools are updated as if it executed (the next arbitrage swap trades after it).
+func refreshArbitrageSwap(pools simulator.PoolStates, arbSwap *simulator.ArbitrageSwap, hotWalletAddress string) bool {
+	swap := arbSwap.SimulatedSwap
+	if !swap.IsExactAmountOut() {
+		balance := config.GetHotWalletArbBalance(hotWalletAddress, swap.TokenIn.Denom)
+		amountIn, amountOut, err := simulator.OptimalArbitrageAmount(pools, swap.Hops(), balance)
+		if err != nil {
+			return false
+		}
+
+		cycle := &simulator.ArbitrageCycle{Hops: swap.Hops(), AmountIn: amountIn, AmountOut: amountOut}
+		*arbSwap = *cycle.ToArbitrageSwap()
+		amountInFloat, err := amountIn.ToDec().Float64()
+		return err == nil && pools.ApplyRoute(cycle.Hops, amountInFloat) == nil
+	}
+
+	//Exact amount out arbitrage must still buy the amount out with at most the max amount in
+	amountIn, err := swap.TokenInMaxAmount.ToDec().Float64()
+	if err != nil {
+		return false
+	}
+	amountOut, err := pools.SimulateRoute(swap.Hops(), amountIn)
+	if err != nil {
+		return false
+	}
+	amountOutInt, err := simulator.IntFromFloat(amountOut)
+	if err != nil || amountOutInt.LT(swap.TokenOut.Amount) {
+		return false
+	}
+	amountIn, err = pools.SwapAmountIn(swap)
+	return err == nil && pools.ApplyRoute(swap.Hops(), amountIn) == nil
+}
+
+// Searches the cached pools for the most profitable arbitrage after the user's swap, in every denom the hot wallet holds.
+// Up to maxArbitrageSwaps cycles are found, each one trading against the pools as the previous cycles leave them.
+// Fills in the simulation's arbitrage swaps if the client didn't provide any, or replaces them if ours earn more.
+// The simulation should already be verified (see VerifySimulation).
+func FindArbitrage(queryClient client.Context, result *simulator.SimulatedSwapResult) error {
+	userSwap := result.SimulatedUserSwap
+	if userSwap == nil {
+		return errors.New("simulation has no user swap")
+	}
+
+	//Make sure the pools the user's swap (and the client's arbitrage) trade through are in the graph
+	pools := CachedPoolStates()
+	routePools, err := GetPoolStates(queryClient, result.PoolIds())
+	if err != nil {
+		return err
+	}
+	for poolId, pool := range routePools {
+		pools[poolId] = pool
+	}
+
+	userAmountIn, err := pools.SwapAmountIn(userSwap)
+	if err != nil {
+		return err
+	}
+	err = pools.ApplyRoute(userSwap.Hops(), userAmountIn)
+	if err != nil {
+		return err
+	}
+
+	hotWalletAddress := queryClient.GetFromAddress().String()
+
+	//The client's arbitrage, sized the way we would submit it
+	clientProfit := sdk.ZeroInt() //in the fee denom
+	clientPools := pools.Clone()
+	for _, arbSwap := range result.GetArbitrageSwaps() {
+		clientSwap := arbSwap.SimulatedSwap
+		if clientSwap.IsExactAmountOut() {
+			//Exact amount out arbitrage is left as the client requested it
+			return nil
+		}
+
+		hops := clientSwap.Hops()
+		amountIn, amountOut, err := simulator.OptimalArbitrageAmount(clientPools, hops, config.GetHotWalletArbBalance(hotWalletAddress, clientSwap.TokenIn.Denom))
+		if err != nil {
+			continue
+		}
+
+		clientCycle := &simulator.ArbitrageCycle{Hops: hops, AmountIn: amountIn, AmountOut: amountOut}
+		profit, err := ToFeeDenom(queryClient, clientCycle.Profit(), clientCycle.Denom())
+		if err == nil {
+			clientProfit = clientProfit.Add(profit)
+		}
+
+		amountInFloat, err := amountIn.ToDec().Float64()
+		if err != nil || clientPools.ApplyRoute(hops, amountInFloat) != nil {
+			break
+		}
+	}
+
+	//Our arbitrage only trades through pools the policy allows
+	policy, err := GetPolicy()
+	if err != nil {
+		return err
+	}
+	pools = policy.FilterPools(pools)
+
+	cycles := []*simulator.ArbitrageCycle{}
+	totalProfit := sdk.ZeroInt() //in the fee denom
+	for len(cycles) < maxArbitrageSwaps {
+		cycle, profit := findBestCycle(queryClient, pools, userSwap.PoolIds(), hotWalletAddress, policy.MaxHops)
+		if cycle == nil {
+			break
+		}
+
+		amountIn, err := cycle.AmountIn.ToDec().Float64()
+		if err != nil || pools.ApplyRoute(cycle.Hops, amountIn) != nil {
+			break
+		}
+
+		cycles = append(cycles, cycle)
+		totalProfit = totalProfit.Add(profit)
+	}
+
+	if len(cycles) == 0 || !totalProfit.GT(clientProfit) {
+		return nil
+	}
+
+	arbSwaps := []*simulator.ArbitrageSwap{}
+	for _, cycle := range cycles {
+		arbSwap := cycle.ToArbitrageSwap()
+		arbSwaps = append(arbSwaps, arbSwap)
+		config.Logger.Info("Found arbitrage route",
+			zap.String("pools", arbSwap.SimulatedSwap.Pools),
+			zap.String("token in", arbSwap.SimulatedSwap.TokenIn.String()),
+			zap.String("estimated profit", arbSwap.EstimatedProfitHumanReadable),
+		)
+	}
+
+	result.SetArbitrageSwaps(arbSwaps)
+	return nil
+}
+
+// The most profitable cycle (compared in the fee denom) in any denom the hot wallet holds, or nil if there is none
+func findBestCycle(queryClient client.Context, pools simulator.PoolStates, touchedPools []uint64, hotWalletAddress string, maxHops int) (*simulator.ArbitrageCycle, sdk.Int) {
+	var best *simulator.ArbitrageCycle
+	bestProfit := sdk.ZeroInt()
+
+	for _, capital := range config.Conf.GetArbitrageCapital() {
+		cycle, err := simulator.FindBestArbitrage(pools, capital.Denom, config.GetHotWalletArbBalance(hotWalletAddress, capital.Denom), touchedPools, maxHops)
+		if err != nil {
+			continue
+		}
+
+		profit, err := ToFeeDenom(queryClient, cycle.Profit(), cycle.Denom())
+		if err != nil {
+			config.Logger.Warn("Could not price arbitrage cycle", zap.Error(err), zap.String("denom", cycle.Denom()))
+			continue
+		}
+
+		if profit.GT(bestProfit) {
+			best = cycle
+			bestProfit = profit
+		}
+	}
+
+	return best, bestProfit
+}
+
+// A complete simulation for swapping tokenIn to denomOut along the best route, including any arbitrage the swap causes.
+// The minimum amount out allows for the given slippage (e.g. .01 for 1%).
+func Quote(queryClient client.Context, tokenIn sdk.Coin, denomOut string, slippage float64, userAddress string) (*simulator.SimulatedSwapResult, error) {
+	if slippage < 0 || slippage >= 1 {
+		return nil, fmt.Errorf("invalid slippage %f", slippage)
+	}
+
+	pools, err := AllPoolStates(queryClient)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPoolDataUnavailable, err.Error())
+	}
+
+	policy, err := GetPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	userSwap, err := simulator.FindBestRoute(policy.FilterPools(pools), tokenIn, denomOut, policy.MaxHops)
+	if err != nil {
+		return nil, err
+	} else if policy.MaxPriceImpact > 0 && userSwap.PriceImpact > policy.MaxPriceImpact {
+		return nil, fmt.Errorf("%w: price impact %.4f exceeds maximum %.4f", simulator.ErrPolicyViolation, userSwap.PriceImpact, policy.MaxPriceImpact)
+	}
+
+	minAmountOut := userSwap.TokenOutAmount.ToDec().Mul(sdk.OneDec().Sub(sdk.MustNewDecFromStr(fmt.Sprintf("%.6f", slippage)))).TruncateInt()
+	userSwap.TokenOutMinAmount = minAmountOut
+
+	result := &simulator.SimulatedSwapResult{SimulatedUserSwap: userSwap, UserAddress: userAddress, SimulationHeight: LatestHeight()}
+	err = FindArbitrage(queryClient, result)
+	if err != nil {
+		config.Logger.Warn("FindArbitrage", zap.Error(err))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,82 @@
+package osmosis
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/tx"
+)
+
+// Every hot wallet TX is signed (and broadcast, if we broadcast it) while holding this lock,
+// so two TXs are never signed with the same account sequence
+var signingLock sync.Mutex
+
+// The account sequence on chain only advances once a TX is committed, so the sequences of TXs we broadcast
+// stay reserved until the chain catches up. A TX that isn't committed within this many blocks was probably
+// dropped from the mempool, and its sequence is used again.
+const broadcastSequenceBlocks = 5
+
+type broadcastSequence struct {
+	next   uint64 //The account's sequence after our broadcast TXs
+	height int64  //Chain height of the latest broadcast
+}
+
+// Sequences reserved by broadcast TXs. Key: account address. Only accessed while holding signingLock.
+var broadcastSequences = map[string]broadcastSequence{}
+
+var ErrSequencesHeld = errors.New("the hot wallet's next sequences are held for a Zenith bundle, try again after the auction block")
+
+// Zenith bundles are signed ahead of their auction with the account's next sequences, so a TX broadcast from the account
+// before the auction block would invalidate the bundle. Key: account address, Value: the latest auction height a bundle was signed for.
+// Only accessed while holding signingLock.
+var heldSequences = map[string]int64{}
+
+// Whether a bundle signed for an auction that hasn't happened yet counts on the account's next sequences.
+// The caller must hold signingLock.
+func sequencesHeld(address string) bool {
+	auctionHeight, ok := heldSequences[address]
+	if ok && LatestHeight() >= auctionHeight {
+		delete(heldSequences, address)
+		return false
+	}
+	return ok
+}
+
+// Whether a Zenith bundle counts on the next sequences of the account with the given address,
+// so any TX broadcast from it now would be rejected with ErrSequencesHeld
+func SequencesHeld(address string) bool {
+	signingLock.Lock()
+	defer signingLock.Unlock()
+	return sequencesHeld(address)
+}
+
+// Holds the account's next sequences until the block at the auction height is committed. The caller must hold signingLock.
+func holdSequences(clientCtx client.Context, auctionHeight int64) {
+	address := clientCtx.GetFromAddress().String()
+	if auctionHeight > heldSequences[address] {
+		heldSequences[address] = auctionHeight
+	}
+}
+
+// The account's next sequence, skipping sequences used by TXs we broadcast that aren't committed yet.
+// The caller must hold signingLock.
+func nextSequence(clientCtx client.Context, txf tx.Factory) tx.Factory {
+	address := clientCtx.GetFromAddress().String()
+	reserved, ok := broadcastSequences[address]
+	if !ok {
+		return txf
+	}
+
+	if reserved.next <= txf.Sequence() || LatestHeight()-reserved.height > broadcastSequenceBlocks {
+		delete(broadcastSequences, address)
+		return txf
+	}
+
+	return txf.WithSequence(reserved.next)
+}
+
+// Reserves the sequence of a TX accepted into the mempool. The caller must hold signingLock.
+func reserveSequence(clientCtx client.Context, sequence uint64) {
+	broadcastSequences[clientCtx.GetFromAddress().String()] = broadcastSequence{next: sequence + 1, height: LatestHeight()}
+}
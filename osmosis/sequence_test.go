@@ -0,0 +1,103 @@
+package osmosis
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func TestNextSequence(t *testing.T) {
+	heightBefore := LatestHeight()
+	defer atomic.StoreInt64(&latestHeight, heightBefore)
+
+	hotWallet := client.Context{}.WithFromAddress(sdk.AccAddress("hot wallet address.."))
+	otherWallet := client.Context{}.WithFromAddress(sdk.AccAddress("other address......."))
+
+	cases := []struct {
+		name          string
+		reserve       []uint64 //Sequences of the TXs broadcast at height 100
+		chainSequence uint64
+		height        int64 //Height when signing the next TX
+		expected      uint64
+	}{
+		{"nothing broadcast", nil, 7, 100, 7},
+		{"one TX broadcast", []uint64{7}, 7, 100, 8},
+		{"several TXs broadcast", []uint64{7, 8, 9}, 7, 101, 10},
+		{"some broadcast TXs committed", []uint64{7, 8, 9}, 9, 101, 10},
+		{"every broadcast TX committed", []uint64{7, 8}, 9, 101, 9},
+		{"broadcast TX dropped from the mempool", []uint64{7}, 7, 101 + broadcastSequenceBlocks, 7},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			broadcastSequences = map[string]broadcastSequence{}
+			atomic.StoreInt64(&latestHeight, 100)
+			for _, sequence := range c.reserve {
+				reserveSequence(hotWallet, sequence)
+			}
+			atomic.StoreInt64(&latestHeight, c.height)
+
+			txf := nextSequence(hotWallet, tx.Factory{}.WithSequence(c.chainSequence))
+			if txf.Sequence() != c.expected {
+				t.Errorf("next sequence is %d, expected %d", txf.Sequence(), c.expected)
+			}
+
+			//Other accounts' sequences are unaffected
+			txf = nextSequence(otherWallet, tx.Factory{}.WithSequence(3))
+			if txf.Sequence() != 3 {
+				t.Errorf("other account's next sequence is %d, expected 3", txf.Sequence())
+			}
+		})
+	}
+}
+
+func TestHeldSequences(t *testing.T) {
+	heightBefore := LatestHeight()
+	defer atomic.StoreInt64(&latestHeight, heightBefore)
+
+	hotWallet := client.Context{}.WithFromAddress(sdk.AccAddress("hot wallet address.."))
+	otherWallet := client.Context{}.WithFromAddress(sdk.AccAddress("other address......."))
+
+	cases := []struct {
+		name     string
+		holds    []int64 //Auction heights of the bundles signed at height 100
+		height   int64   //Height when broadcasting the next TX
+		expected bool
+	}{
+		{"no bundle", nil, 100, false},
+		{"bundle for the next block", []int64{101}, 100, true},
+		{"auction block committed", []int64{101}, 101, false},
+		{"bundles for several blocks", []int64{103, 101}, 102, true},
+		{"every auction block committed", []int64{103, 101}, 103, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			heldSequences = map[string]int64{}
+			atomic.StoreInt64(&latestHeight, 100)
+			for _, auctionHeight := range c.holds {
+				holdSequences(hotWallet, auctionHeight)
+			}
+			atomic.StoreInt64(&latestHeight, c.height)
+
+			if held := SequencesHeld(hotWallet.GetFromAddress().String()); held != c.expected {
+				t.Errorf("sequences held is %t, expected %t", held, c.expected)
+			}
+			if SequencesHeld(otherWallet.GetFromAddress().String()) {
+				t.Error("other account's sequences are held")
+			}
+
+			//Broadcasts are refused before the TX is signed
+			if c.expected {
+				_, _, err := signBroadcastTx(hotWallet, nil, 0, 0)
+				if !errors.Is(err, ErrSequencesHeld) {
+					t.Errorf("expected ErrSequencesHeld, got %v", err)
+				}
+			}
+		})
+	}
+}
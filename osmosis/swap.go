@@ -14,3 +14,13 @@ func BuildSwapExactAmountIn(tokenIn cosmosSdk.Coin, tokenOutMinAmt cosmosSdk.Int
 		TokenOutMinAmount: tokenOutMinAmt,
 	}
 }
+
+func BuildSwapExactAmountOut(tokenOut cosmosSdk.Coin, tokenInMaxAmt cosmosSdk.Int, routes []types.SwapAmountOutRoute, address string) cosmosSdk.Msg {
+
+	return &types.MsgSwapExactAmountOut{
+		Sender:           address,
+		Routes:           routes,
+		TokenInMaxAmount: tokenInMaxAmt,
+		TokenOut:         tokenOut,
+	}
+}
@@ -2,6 +2,7 @@ package osmosis
 
 import (
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/DefiantLabs/RedpointSwap/config"
@@ -27,6 +28,12 @@ func TrackBlocks(websocketEndpoint string, newBlocks chan int64) {
 }
 
 var averageBlockTime int64 = 0 //average number of milliseconds between each block
+var latestHeight int64 = 0     //most recent block height we were notified about (read and written with sync/atomic)
+
+// Most recent block height (0 until the first block is received)
+func LatestHeight() int64 {
+	return atomic.LoadInt64(&latestHeight)
+}
 
 func ProcessNewBlock(height chan int64, subscribers []func(height int64, avgTimeBetweenBlocks int64)) {
 	trackedBlockTimes := []int64{} //number of milliseconds between blocks
@@ -56,13 +63,14 @@ func ProcessNewBlock(height chan int64, subscribers []func(height int64, avgTime
 		averageBlockTime = total / int64(len(trackedBlockTimes))
 
 		lastHeight = newHeight
+		atomic.StoreInt64(&latestHeight, newHeight)
 		lastBlockStart = time.Now()
 
-		go func() {
+		go func(height int64, avgBlockTime int64) {
 			//Notify subscribers about the new block
 			for _, subscriber := range subscribers {
-				subscriber(lastHeight, averageBlockTime)
+				subscriber(height, avgBlockTime)
 			}
-		}()
+		}(newHeight, averageBlockTime)
 	}
 }
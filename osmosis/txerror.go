@@ -0,0 +1,83 @@
+package osmosis
+
+import (
+	"regexp"
+	"strconv"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	gammTypes "github.com/osmosis-labs/osmosis/v13/x/gamm/types"
+)
+
+// Stable error codes for common TX failures. These are part of the status API, don't rename them.
+const (
+	TxErrorSlippageExceeded  = "SLIPPAGE_EXCEEDED"  //The swap would have received less than TokenOutMinAmount (or needed more than TokenInMaxAmount)
+	TxErrorInsufficientFunds = "INSUFFICIENT_FUNDS" //The sender's balance was too low
+	TxErrorSequenceMismatch  = "SEQUENCE_MISMATCH"  //The account sequence changed since the TX was signed (e.g. another TX landed first)
+	TxErrorOutOfGas          = "OUT_OF_GAS"
+	TxErrorInsufficientFee   = "INSUFFICIENT_FEE"
+	TxErrorUnauthorized      = "UNAUTHORIZED" //E.g. the user's authz grant is missing or expired
+	TxErrorUnknown           = "UNKNOWN"
+)
+
+// SDK and gamm errors we map to stable error codes. Anything else is TxErrorUnknown.
+var txErrorCodes = []struct {
+	err  *sdkerrors.Error
+	code string
+}{
+	{gammTypes.ErrLimitMinAmount, TxErrorSlippageExceeded},
+	{gammTypes.ErrLimitMaxAmount, TxErrorSlippageExceeded},
+	{sdkerrors.ErrInsufficientFunds, TxErrorInsufficientFunds},
+	{sdkerrors.ErrWrongSequence, TxErrorSequenceMismatch},
+	{sdkerrors.ErrInvalidSequence, TxErrorSequenceMismatch},
+	{sdkerrors.ErrOutOfGas, TxErrorOutOfGas},
+	{sdkerrors.ErrInsufficientFee, TxErrorInsufficientFee},
+	{sdkerrors.ErrUnauthorized, TxErrorUnauthorized},
+}
+
+// The SDK prefixes a failed message's error with the index of the message
+var failedMessageRegex = regexp.MustCompile(`^failed to execute message; message index: (\d+): `)
+
+// Why a TX failed on chain (or was rejected by the node)
+type TxFailure struct {
+	ErrorCode    string //Stable error code, e.g. SLIPPAGE_EXCEEDED (see the TxError constants)
+	Code         uint32 //ABCI code
+	Codespace    string
+	MessageIndex int    //Index of the message that failed, -1 if the TX failed before executing messages (e.g. ante handler errors)
+	Log          string //The raw log without the failed message prefix
+}
+
+// Failure details for the TX, or nil if the TX succeeded
+func ParseTxFailure(txResponse *sdk.TxResponse) *TxFailure {
+	if txResponse == nil || txResponse.Code == 0 {
+		return nil
+	}
+
+	failure := &TxFailure{
+		ErrorCode:    GetTxErrorCode(txResponse.Codespace, txResponse.Code),
+		Code:         txResponse.Code,
+		Codespace:    txResponse.Codespace,
+		MessageIndex: -1,
+		Log:          txResponse.RawLog,
+	}
+
+	if match := failedMessageRegex.FindStringSubmatch(txResponse.RawLog); match != nil {
+		messageIndex, err := strconv.Atoi(match[1])
+		if err == nil {
+			failure.MessageIndex = messageIndex
+			failure.Log = txResponse.RawLog[len(match[0]):]
+		}
+	}
+
+	return failure
+}
+
+// The stable error code for the ABCI codespace and code
+func GetTxErrorCode(codespace string, code uint32) string {
+	for _, txErr := range txErrorCodes {
+		if txErr.err.Codespace() == codespace && txErr.err.ABCICode() == code {
+			return txErr.code
+		}
+	}
+	return TxErrorUnknown
+}
@@ -0,0 +1,128 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+
+	cosmosTypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+var ErrPolicyViolation = errors.New("rejected by route policy")
+
+// Limits on the pools and denoms swaps may trade through. Zero values don't restrict anything.
+type Policy struct {
+	AllowedPools     map[uint64]bool   //If not empty, only these pools may be traded through
+	DeniedPools      map[uint64]bool   //These pools may never be traded through
+	AllowedDenoms    map[string]bool   //If not empty, only these denoms may be traded
+	MinPoolLiquidity cosmosTypes.Coins //If not empty, every pool must hold at least this much of one of these denoms
+	MaxHops          int               //Most pools a single swap may trade through. 0 uses MaxRouteHops for user swaps and MaxCycleHops for arbitrage.
+	MaxPriceImpact   float64           //Largest price impact allowed for the user's swap (e.g. .05 for 5%)
+}
+
+// Checks the user swap and every arbitrage swap in the simulation. Pools should be the state before the user's swap.
+func (policy *Policy) CheckSimulation(pools PoolStates, result *SimulatedSwapResult) error {
+	if result.SimulatedUserSwap == nil {
+		return errors.New("simulation has no user swap")
+	}
+
+	err := policy.CheckSwap(pools, result.SimulatedUserSwap, policy.maxHops(MaxRouteHops))
+	if err != nil {
+		return fmt.Errorf("user swap %w", err)
+	}
+
+	if policy.MaxPriceImpact > 0 {
+		userSwap := result.SimulatedUserSwap
+		_, priceImpact, err := pools.PriceImpact(userSwap.Hops(), toFloat(userSwap.GetTokenIn().Amount.ToDec()))
+		if err != nil {
+			return fmt.Errorf("user swap: %s", err.Error())
+		} else if priceImpact > policy.MaxPriceImpact {
+			return fmt.Errorf("user swap %w: price impact %.4f exceeds maximum %.4f", ErrPolicyViolation, priceImpact, policy.MaxPriceImpact)
+		}
+	}
+
+	for i, arbSwap := range result.GetArbitrageSwaps() {
+		err = policy.CheckSwap(pools, arbSwap.SimulatedSwap, policy.maxHops(MaxCycleHops))
+		if err != nil {
+			return fmt.Errorf("arbitrage swap %d %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// Checks that the swap trades through at most maxHops pools, and every pool and denom it trades through
+func (policy *Policy) CheckSwap(pools PoolStates, swap *SimulatedSwap, maxHops int) error {
+	hops := swap.Hops()
+	if len(hops) == 0 {
+		return fmt.Errorf("%w: no routes", ErrPolicyViolation)
+	} else if maxHops > 0 && len(hops) > maxHops {
+		return fmt.Errorf("%w: %d hops exceeds maximum %d", ErrPolicyViolation, len(hops), maxHops)
+	}
+
+	for _, hop := range hops {
+		if !policy.denomAllowed(hop.DenomIn) {
+			return fmt.Errorf("%w: denom %s is not allowed", ErrPolicyViolation, hop.DenomIn)
+		} else if !policy.denomAllowed(hop.DenomOut) {
+			return fmt.Errorf("%w: denom %s is not allowed", ErrPolicyViolation, hop.DenomOut)
+		}
+
+		pool, ok := pools[hop.PoolId]
+		if !ok {
+			return fmt.Errorf("no pool data for pool %d", hop.PoolId)
+		}
+		err := policy.checkPool(pool)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// The policy's MaxHops, or defaultMaxHops if the policy doesn't set one
+func (policy *Policy) maxHops(defaultMaxHops int) int {
+	if policy.MaxHops > 0 {
+		return policy.MaxHops
+	}
+	return defaultMaxHops
+}
+
+// Only the pools the policy allows swaps to trade through
+func (policy *Policy) FilterPools(pools PoolStates) PoolStates {
+	filtered := PoolStates{}
+	for poolId, pool := range pools {
+		if policy.checkPool(pool) == nil {
+			filtered[poolId] = pool
+		}
+	}
+	return filtered
+}
+
+func (policy *Policy) checkPool(pool *PoolState) error {
+	if len(policy.AllowedPools) > 0 && !policy.AllowedPools[pool.PoolId] {
+		return fmt.Errorf("%w: pool %d is not allowed", ErrPolicyViolation, pool.PoolId)
+	} else if policy.DeniedPools[pool.PoolId] {
+		return fmt.Errorf("%w: pool %d is denied", ErrPolicyViolation, pool.PoolId)
+	}
+
+	for denom := range pool.Assets {
+		if !policy.denomAllowed(denom) {
+			return fmt.Errorf("%w: pool %d holds denom %s, which is not allowed", ErrPolicyViolation, pool.PoolId, denom)
+		}
+	}
+
+	if policy.MinPoolLiquidity.Empty() {
+		return nil
+	}
+	for _, minReserve := range policy.MinPoolLiquidity {
+		asset, ok := pool.Assets[minReserve.Denom]
+		if ok && asset.Reserve.GTE(minReserve.Amount) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: pool %d has less liquidity than the minimum %s", ErrPolicyViolation, pool.PoolId, policy.MinPoolLiquidity)
+}
+
+func (policy *Policy) denomAllowed(denom string) bool {
+	return len(policy.AllowedDenoms) == 0 || policy.AllowedDenoms[denom]
+}
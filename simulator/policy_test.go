@@ -0,0 +1,129 @@
+package simulator_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DefiantLabs/RedpointSwap/simulator"
+	cosmosSdk "github.com/cosmos/cosmos-sdk/types"
+	gammTypes "github.com/osmosis-labs/osmosis/v13/x/gamm/types"
+)
+
+func TestPolicyCheckSimulation(t *testing.T) {
+	pools := poolStates(t,
+		newBalancerPool(t, 1, "0.002", balancerAsset("uosmo", "2500000000000", 1), balancerAsset("uatom", "250000000000", 1)),
+		newBalancerPool(t, 2, "0.002", balancerAsset("uosmo", "2600000000000", 1), balancerAsset("uatom", "240000000000", 1)),
+		newBalancerPool(t, 3, "0.002", balancerAsset("uatom", "250000000000", 1), balancerAsset("uusdc", "1250000000000", 1)),
+	)
+
+	//User swap uosmo -> uatom -> uusdc through pools 1 and 3, arbitrage uosmo -> uatom -> uosmo through pools 2 and 1
+	result := &simulator.SimulatedSwapResult{
+		SimulatedUserSwap: &simulator.SimulatedSwap{
+			TokenIn:       cosmosSdk.NewCoin("uosmo", amount("1000000000")),
+			Routes:        gammTypes.SwapAmountInRoutes{{PoolId: 1, TokenOutDenom: "uatom"}, {PoolId: 3, TokenOutDenom: "uusdc"}},
+			TokenOutDenom: "uusdc",
+		},
+	}
+	result.SetArbitrageSwaps([]*simulator.ArbitrageSwap{{
+		SimulatedSwap: &simulator.SimulatedSwap{
+			TokenIn:       cosmosSdk.NewCoin("uosmo", amount("1000000")),
+			Routes:        gammTypes.SwapAmountInRoutes{{PoolId: 2, TokenOutDenom: "uatom"}, {PoolId: 1, TokenOutDenom: "uosmo"}},
+			TokenOutDenom: "uosmo",
+		},
+	}})
+
+	cases := []struct {
+		name    string
+		policy  simulator.Policy
+		allowed bool
+	}{
+		{"empty policy", simulator.Policy{}, true},
+		{"allowed pools include every pool", simulator.Policy{AllowedPools: map[uint64]bool{1: true, 2: true, 3: true}}, true},
+		{"allowed pools exclude the user's pool", simulator.Policy{AllowedPools: map[uint64]bool{1: true, 2: true}}, false},
+		{"allowed pools exclude the arbitrage pool", simulator.Policy{AllowedPools: map[uint64]bool{1: true, 3: true}}, false},
+		{"denied pool not traded through", simulator.Policy{DeniedPools: map[uint64]bool{4: true}}, true},
+		{"denied user pool", simulator.Policy{DeniedPools: map[uint64]bool{3: true}}, false},
+		{"denied arbitrage pool", simulator.Policy{DeniedPools: map[uint64]bool{2: true}}, false},
+		{"denied pool overrides allowed pool", simulator.Policy{AllowedPools: map[uint64]bool{1: true, 2: true, 3: true}, DeniedPools: map[uint64]bool{1: true}}, false},
+		{"allowed denoms include every denom", simulator.Policy{AllowedDenoms: map[string]bool{"uosmo": true, "uatom": true, "uusdc": true}}, true},
+		{"allowed denoms exclude a pool denom", simulator.Policy{AllowedDenoms: map[string]bool{"uosmo": true, "uatom": true}}, false},
+		{"within the maximum hops", simulator.Policy{MaxHops: 2}, true},
+		{"above the maximum hops", simulator.Policy{MaxHops: 1}, false},
+		{"above the minimum liquidity", simulator.Policy{MinPoolLiquidity: cosmosSdk.NewCoins(cosmosSdk.NewCoin("uatom", amount("100000000000")))}, true},
+		{"below the minimum liquidity", simulator.Policy{MinPoolLiquidity: cosmosSdk.NewCoins(cosmosSdk.NewCoin("uatom", amount("245000000000")))}, false},
+		{"within the maximum price impact", simulator.Policy{MaxPriceImpact: 0.01}, true},
+		{"above the maximum price impact", simulator.Policy{MaxPriceImpact: 0.0001}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.policy.CheckSimulation(pools, result)
+			if c.allowed && err != nil {
+				t.Errorf("expected the simulation to be allowed, got %s", err.Error())
+			} else if !c.allowed && !errors.Is(err, simulator.ErrPolicyViolation) {
+				t.Errorf("expected ErrPolicyViolation, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPolicyFilterPools(t *testing.T) {
+	pools := poolStates(t,
+		newBalancerPool(t, 1, "0.002", balancerAsset("uosmo", "2500000000000", 1), balancerAsset("uatom", "250000000000", 1)),
+		newBalancerPool(t, 2, "0.002", balancerAsset("uosmo", "2600000000000", 1), balancerAsset("uatom", "240000000000", 1)),
+		newBalancerPool(t, 3, "0.002", balancerAsset("uatom", "250000000000", 1), balancerAsset("uusdc", "1250000000000", 1)),
+	)
+
+	policy := simulator.Policy{AllowedPools: map[uint64]bool{1: true, 2: true, 3: true}, DeniedPools: map[uint64]bool{2: true}, AllowedDenoms: map[string]bool{"uosmo": true, "uatom": true}}
+	filtered := policy.FilterPools(pools)
+	if len(filtered) != 1 || filtered[1] == nil {
+		t.Errorf("expected only pool 1, got %d pools", len(filtered))
+	}
+}
+
+func TestPolicyDefaultMaxHops(t *testing.T) {
+	osmoPools := []gammTypes.PoolI{}
+	for poolId := uint64(1); poolId <= 6; poolId++ {
+		osmoPools = append(osmoPools, newBalancerPool(t, poolId, "0.002", balancerAsset("uosmo", "2500000000000", 1), balancerAsset("uatom", "250000000000", 1)))
+	}
+	pools := poolStates(t, osmoPools...)
+
+	//Trades back and forth between uosmo and uatom through pools 1 to hops
+	swap := func(hops int) *simulator.SimulatedSwap {
+		routes := gammTypes.SwapAmountInRoutes{}
+		for i := 0; i < hops; i++ {
+			routes = append(routes, gammTypes.SwapAmountInRoute{PoolId: uint64(i + 1), TokenOutDenom: []string{"uatom", "uosmo"}[i%2]})
+		}
+		return &simulator.SimulatedSwap{TokenIn: cosmosSdk.NewCoin("uosmo", amount("1000000")), Routes: routes, TokenOutDenom: routes[len(routes)-1].TokenOutDenom}
+	}
+
+	cases := []struct {
+		name      string
+		policy    simulator.Policy
+		userHops  int
+		arbHops   int
+		allowed   bool
+		violation string
+	}{
+		{"within both defaults", simulator.Policy{}, simulator.MaxRouteHops, simulator.MaxCycleHops, true, ""},
+		{"user swap above the default", simulator.Policy{}, simulator.MaxRouteHops + 1, 2, false, "user swap"},
+		{"arbitrage above the default", simulator.Policy{}, 2, simulator.MaxCycleHops + 1, false, "arbitrage swap"},
+		{"configured maximum replaces the arbitrage default", simulator.Policy{MaxHops: 4}, 2, simulator.MaxCycleHops, false, "arbitrage swap"},
+		{"configured maximum replaces the user default", simulator.Policy{MaxHops: 6}, simulator.MaxRouteHops + 1, 6, true, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result := &simulator.SimulatedSwapResult{SimulatedUserSwap: swap(c.userHops)}
+			result.SetArbitrageSwaps([]*simulator.ArbitrageSwap{{SimulatedSwap: swap(c.arbHops)}})
+
+			err := c.policy.CheckSimulation(pools, result)
+			if c.allowed && err != nil {
+				t.Errorf("expected the simulation to be allowed, got %s", err.Error())
+			} else if !c.allowed && (!errors.Is(err, simulator.ErrPolicyViolation) || !strings.HasPrefix(err.Error(), c.violation)) {
+				t.Errorf("expected ErrPolicyViolation for the %s, got %v", c.violation, err)
+			}
+		})
+	}
+}
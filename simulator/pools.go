@@ -0,0 +1,349 @@
+package simulator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	cosmosTypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/osmosis-labs/osmosis/v13/x/gamm/types"
+)
+
+// A swap will never use more than this fraction of a pool's reserve of the token in.
+// Larger swaps are too sensitive to other trades landing in the same block.
+const MaxReserveRatio = 0.3
+
+// Osmosis pool models the simulator can do swap math for
+const (
+	PoolTypeBalancer   = "balancer"
+	PoolTypeStableswap = "stableswap"
+)
+
+// Most bisection steps used to solve the stableswap curve (the bisection stops early once float64 precision is reached)
+const stableswapIterations = 256
+
+// Reserves and weights of a single pool, as needed to simulate swaps through it
+type PoolState struct {
+	PoolId   uint64
+	PoolType string //PoolTypeBalancer or PoolTypeStableswap
+	SwapFee  cosmosTypes.Dec
+	Assets   map[string]PoolAsset //Key: denom
+	Height   int64                //Chain height the pool state was read at
+}
+
+type PoolAsset struct {
+	Reserve       cosmosTypes.Int
+	Weight        cosmosTypes.Int //Only for balancer pools
+	ScalingFactor uint64          //Only for stableswap pools
+}
+
+// One step of a route: trade DenomIn for DenomOut in the given pool
+type Hop struct {
+	PoolId   uint64
+	DenomIn  string
+	DenomOut string
+}
+
+// The pool state of every pool on a route. Key: pool ID
+type PoolStates map[uint64]*PoolState
+
+// Copy of the pool that can be modified without changing the original
+func (pool *PoolState) Clone() *PoolState {
+	clone := &PoolState{PoolId: pool.PoolId, PoolType: pool.PoolType, SwapFee: pool.SwapFee, Assets: map[string]PoolAsset{}, Height: pool.Height}
+	for denom, asset := range pool.Assets {
+		clone.Assets[denom] = asset
+	}
+	return clone
+}
+
+// Amount of denomOut the pool will pay for amountIn of denomIn, using the math for the pool's type
+func (pool *PoolState) CalcOutGivenIn(denomIn string, denomOut string, amountIn float64) (float64, error) {
+	assetIn, okIn := pool.Assets[denomIn]
+	assetOut, okOut := pool.Assets[denomOut]
+	if !okIn || !okOut {
+		return 0, fmt.Errorf("pool %d does not trade %s for %s", pool.PoolId, denomIn, denomOut)
+	}
+
+	reserveIn := toFloat(assetIn.Reserve.ToDec())
+	reserveOut := toFloat(assetOut.Reserve.ToDec())
+	if reserveIn <= 0 || reserveOut <= 0 {
+		return 0, fmt.Errorf("pool %d has no liquidity for %s/%s", pool.PoolId, denomIn, denomOut)
+	} else if amountIn > reserveIn*MaxReserveRatio {
+		return 0, fmt.Errorf("swap of %f%s exceeds pool %d liquidity", amountIn, denomIn, pool.PoolId)
+	}
+
+	amountInAfterFee := amountIn * (1 - toFloat(pool.SwapFee))
+
+	switch pool.PoolType {
+	case PoolTypeStableswap:
+		return pool.stableswapOutGivenIn(denomIn, denomOut, amountInAfterFee)
+	case PoolTypeBalancer, "":
+		//out = reserveOut * (1 - (reserveIn / (reserveIn + in*(1-fee)))^(weightIn/weightOut))
+		weightIn := toFloat(assetIn.Weight.ToDec())
+		weightOut := toFloat(assetOut.Weight.ToDec())
+		if weightIn <= 0 || weightOut <= 0 {
+			return 0, fmt.Errorf("pool %d has invalid weights for %s/%s", pool.PoolId, denomIn, denomOut)
+		}
+		return reserveOut * (1 - math.Pow(reserveIn/(reserveIn+amountInAfterFee), weightIn/weightOut)), nil
+	default:
+		return 0, fmt.Errorf("pool %d has unsupported pool type %s", pool.PoolId, pool.PoolType)
+	}
+}
+
+// Osmosis stableswap pools keep x*y*(x^2 + y^2 + w) constant, where x and y are the scaled reserves of the traded assets
+// and w is the sum of squares of the other scaled reserves. Solves for the new reserve of denomOut with a bisection.
+func (pool *PoolState) stableswapOutGivenIn(denomIn string, denomOut string, amountInAfterFee float64) (float64, error) {
+	scaled := func(denom string) (float64, float64, error) {
+		asset := pool.Assets[denom]
+		if asset.ScalingFactor == 0 {
+			return 0, 0, fmt.Errorf("pool %d has no scaling factor for %s", pool.PoolId, denom)
+		}
+		factor := float64(asset.ScalingFactor)
+		return math.Floor(toFloat(asset.Reserve.ToDec()) / factor), factor, nil
+	}
+
+	y, scaleIn, err := scaled(denomIn)
+	if err != nil {
+		return 0, err
+	}
+	x, scaleOut, err := scaled(denomOut)
+	if err != nil {
+		return 0, err
+	}
+
+	w := 0.0
+	for denom := range pool.Assets {
+		if denom == denomIn || denom == denomOut {
+			continue
+		}
+		reserve, _, err := scaled(denom)
+		if err != nil {
+			return 0, err
+		}
+		w += reserve * reserve
+	}
+
+	if x <= 0 || y <= 0 {
+		return 0, fmt.Errorf("pool %d has no liquidity for %s/%s", pool.PoolId, denomIn, denomOut)
+	}
+
+	cfmm := func(x float64, y float64) float64 {
+		return x * y * (x*x + y*y + w)
+	}
+
+	k := cfmm(x, y)
+	yNew := y + math.Floor(amountInAfterFee/scaleIn)
+
+	//The curve is increasing in x, so the new reserve is between 0 and the current reserve
+	lower, upper := 0.0, x
+	for i := 0; i < stableswapIterations; i++ {
+		mid := (lower + upper) / 2
+		if mid <= lower || mid >= upper {
+			break //The interval can't shrink any further in float64
+		}
+		if cfmm(mid, yNew) < k {
+			lower = mid
+		} else {
+			upper = mid
+		}
+	}
+
+	return (x - upper) * scaleOut, nil
+}
+
+// Updates the pool reserves as if the swap was executed on chain
+func (pool *PoolState) ApplySwap(denomIn string, denomOut string, amountIn float64) (float64, error) {
+	amountOut, err := pool.CalcOutGivenIn(denomIn, denomOut, amountIn)
+	if err != nil {
+		return 0, err
+	}
+
+	amountInInt, err := IntFromFloat(amountIn)
+	if err != nil {
+		return 0, err
+	}
+	amountOutInt, err := IntFromFloat(amountOut)
+	if err != nil {
+		return 0, err
+	}
+
+	assetIn := pool.Assets[denomIn]
+	assetOut := pool.Assets[denomOut]
+	assetIn.Reserve = assetIn.Reserve.Add(amountInInt)
+	assetOut.Reserve = assetOut.Reserve.Sub(amountOutInt)
+	pool.Assets[denomIn] = assetIn
+	pool.Assets[denomOut] = assetOut
+	return amountOut, nil
+}
+
+func (pools PoolStates) Clone() PoolStates {
+	clone := PoolStates{}
+	for id, pool := range pools {
+		clone[id] = pool.Clone()
+	}
+	return clone
+}
+
+// Oldest height any of the pools were read at
+func (pools PoolStates) Height() int64 {
+	var height int64
+	for _, pool := range pools {
+		if height == 0 || pool.Height < height {
+			height = pool.Height
+		}
+	}
+	return height
+}
+
+// Amount received at the end of the route for the given amount in
+func (pools PoolStates) SimulateRoute(hops []Hop, amountIn float64) (float64, error) {
+	amount := amountIn
+	for _, hop := range hops {
+		pool, ok := pools[hop.PoolId]
+		if !ok {
+			return 0, fmt.Errorf("no pool data for pool %d", hop.PoolId)
+		}
+
+		var err error
+		amount, err = pool.CalcOutGivenIn(hop.DenomIn, hop.DenomOut, amount)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return amount, nil
+}
+
+// Smallest amount in (up to maxAmountIn) that buys the given amount out through the route, as spent by a MsgSwapExactAmountOut
+func (pools PoolStates) AmountInForOut(hops []Hop, amountOut float64, maxAmountIn float64) float64 {
+	//The amount out only grows with the amount in, so a binary search finds the amount in
+	lower, upper := 0.0, maxAmountIn
+	for i := 0; i < sizingIterations && upper-lower > 1; i++ {
+		mid := (lower + upper) / 2
+		if out, err := pools.SimulateRoute(hops, mid); err == nil && math.Floor(out) >= amountOut {
+			upper = mid
+		} else {
+			lower = mid
+		}
+	}
+
+	return math.Ceil(upper)
+}
+
+// Amount the swap spends on chain. For MsgSwapExactAmountOut this is the amount in needed to buy the exact amount out,
+// which may be well under the maximum amount in.
+func (pools PoolStates) SwapAmountIn(swap *SimulatedSwap) (float64, error) {
+	amountIn := toFloat(swap.GetTokenIn().Amount.ToDec())
+	if !swap.IsExactAmountOut() {
+		return amountIn, nil
+	}
+
+	amountOut := toFloat(swap.TokenOut.Amount.ToDec())
+	if _, err := pools.SimulateRoute(swap.Hops(), amountIn); err != nil {
+		return 0, err
+	}
+	return pools.AmountInForOut(swap.Hops(), amountOut, amountIn), nil
+}
+
+// Amount received at the end of the route and how much the trade moves the price (e.g. .025 for 2.5%).
+// Price impact compares the execution price to the marginal price (both include swap fees).
+func (pools PoolStates) PriceImpact(hops []Hop, amountIn float64) (float64, float64, error) {
+	amountOut, err := pools.SimulateRoute(hops, amountIn)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	rate := 1.0
+	for _, hop := range hops {
+		hopRate, err := pools[hop.PoolId].marginalRate(hop.DenomIn, hop.DenomOut)
+		if err != nil {
+			return 0, 0, err
+		}
+		rate *= hopRate
+	}
+
+	priceImpact := 1 - (amountOut/amountIn)/rate
+	if priceImpact < 0 {
+		priceImpact = 0
+	}
+	return amountOut, priceImpact, nil
+}
+
+// Updates the pool reserves as if a trade through the route was executed on chain
+func (pools PoolStates) ApplyRoute(hops []Hop, amountIn float64) error {
+	amount := amountIn
+	for _, hop := range hops {
+		pool, ok := pools[hop.PoolId]
+		if !ok {
+			return fmt.Errorf("no pool data for pool %d", hop.PoolId)
+		}
+
+		var err error
+		amount, err = pool.ApplySwap(hop.DenomIn, hop.DenomOut, amount)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// The hops of a MsgSwapExactAmountIn route starting with denomIn
+func HopsFromRoutes(denomIn string, routes types.SwapAmountInRoutes) []Hop {
+	hops := []Hop{}
+	for _, route := range routes {
+		hops = append(hops, Hop{PoolId: route.PoolId, DenomIn: denomIn, DenomOut: route.TokenOutDenom})
+		denomIn = route.TokenOutDenom
+	}
+	return hops
+}
+
+// The MsgSwapExactAmountIn routes for the hops
+func RoutesFromHops(hops []Hop) types.SwapAmountInRoutes {
+	routes := types.SwapAmountInRoutes{}
+	for _, hop := range hops {
+		routes = append(routes, types.SwapAmountInRoute{PoolId: hop.PoolId, TokenOutDenom: hop.DenomOut})
+	}
+	return routes
+}
+
+// The hops of a MsgSwapExactAmountOut route ending with denomOut
+func HopsFromOutRoutes(routes types.SwapAmountOutRoutes, denomOut string) []Hop {
+	hops := []Hop{}
+	for i, route := range routes {
+		hopDenomOut := denomOut
+		if i+1 < len(routes) {
+			hopDenomOut = routes[i+1].TokenInDenom
+		}
+		hops = append(hops, Hop{PoolId: route.PoolId, DenomIn: route.TokenInDenom, DenomOut: hopDenomOut})
+	}
+	return hops
+}
+
+// The hops the swap trades through, in order
+func (swap *SimulatedSwap) Hops() []Hop {
+	if swap.IsExactAmountOut() {
+		return HopsFromOutRoutes(swap.OutRoutes, swap.TokenOut.Denom)
+	}
+
+	return HopsFromRoutes(swap.TokenIn.Denom, swap.Routes)
+}
+
+// The amount rounded down to an Int. Amounts of 18 decimal denoms (e.g. wei) don't fit in an int64, so the conversion goes through big.Float.
+func IntFromFloat(amount float64) (cosmosTypes.Int, error) {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) || amount < 0 {
+		return cosmosTypes.Int{}, fmt.Errorf("invalid amount %f", amount)
+	}
+
+	i, _ := big.NewFloat(math.Floor(amount)).Int(nil)
+	if i.BitLen() > 255 {
+		return cosmosTypes.Int{}, fmt.Errorf("amount %f is out of range", amount)
+	}
+	return cosmosTypes.NewIntFromBigInt(i), nil
+}
+
+// The closest float to the Dec. Unlike Dec.Float64 this can't fail, any Dec is within the float64 range.
+func toFloat(dec cosmosTypes.Dec) float64 {
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(dec.BigInt()), new(big.Float).SetInt(cosmosTypes.OneDec().BigInt())).Float64()
+	return f
+}
@@ -0,0 +1,379 @@
+package simulator_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/DefiantLabs/RedpointSwap/osmosis"
+	"github.com/DefiantLabs/RedpointSwap/simulator"
+	cosmosSdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/osmosis-labs/osmosis/v13/x/gamm/pool-models/balancer"
+	"github.com/osmosis-labs/osmosis/v13/x/gamm/pool-models/stableswap"
+	gammTypes "github.com/osmosis-labs/osmosis/v13/x/gamm/types"
+)
+
+// Simulated amounts may differ from Osmosis by this fraction (Osmosis approximates pow and the stableswap curve differently)
+const osmosisTolerance = 1e-6
+
+func amount(s string) cosmosSdk.Int {
+	i, ok := cosmosSdk.NewIntFromString(s)
+	if !ok {
+		panic("invalid amount " + s)
+	}
+	return i
+}
+
+func balancerAsset(denom string, reserve string, weight int64) balancer.PoolAsset {
+	return balancer.PoolAsset{Token: cosmosSdk.NewCoin(denom, amount(reserve)), Weight: cosmosSdk.NewInt(weight)}
+}
+
+func newBalancerPool(t testing.TB, poolId uint64, swapFee string, assets ...balancer.PoolAsset) gammTypes.PoolI {
+	params := balancer.PoolParams{SwapFee: cosmosSdk.MustNewDecFromStr(swapFee), ExitFee: cosmosSdk.ZeroDec()}
+	pool, err := balancer.NewBalancerPool(poolId, params, assets, "", time.Unix(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pool
+}
+
+func newStableswapPool(t testing.TB, poolId uint64, swapFee string, liquidity cosmosSdk.Coins, scalingFactors []uint64) gammTypes.PoolI {
+	params := stableswap.PoolParams{SwapFee: cosmosSdk.MustNewDecFromStr(swapFee), ExitFee: cosmosSdk.ZeroDec()}
+	pool, err := stableswap.NewStableswapPool(poolId, params, liquidity, scalingFactors, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pool
+}
+
+// The simulator's state for the pools, read the same way as pools on chain
+func poolStates(t testing.TB, pools ...gammTypes.PoolI) simulator.PoolStates {
+	states := simulator.PoolStates{}
+	for _, pool := range pools {
+		state, err := osmosis.ToPoolState(pool)
+		if err != nil {
+			t.Fatal(err)
+		}
+		states[state.PoolId] = state
+	}
+	return states
+}
+
+func withinTolerance(actual float64, expected float64, tolerance float64) bool {
+	return math.Abs(actual-expected) <= math.Max(1, expected*tolerance)
+}
+
+func TestCalcOutGivenInMatchesOsmosis(t *testing.T) {
+	cases := []struct {
+		name     string
+		pool     gammTypes.PoolI
+		tokenIn  cosmosSdk.Coin
+		denomOut string
+	}{
+		{
+			"balancer 50/50",
+			newBalancerPool(t, 1, "0.002", balancerAsset("uatom", "250000000000", 1), balancerAsset("uosmo", "2500000000000", 1)),
+			cosmosSdk.NewCoin("uosmo", amount("1000000000")), "uatom",
+		},
+		{
+			"balancer 80/20",
+			newBalancerPool(t, 2, "0.003", balancerAsset("uion", "40000000000", 20), balancerAsset("uosmo", "900000000000", 80)),
+			cosmosSdk.NewCoin("uion", amount("25000000")), "uosmo",
+		},
+		{
+			"balancer near the maximum reserve ratio",
+			newBalancerPool(t, 3, "0.002", balancerAsset("uatom", "250000000000", 1), balancerAsset("uosmo", "2500000000000", 1)),
+			cosmosSdk.NewCoin("uatom", amount("74000000000")), "uosmo",
+		},
+		{
+			"balancer with an 18 decimal denom",
+			newBalancerPool(t, 4, "0.002", balancerAsset("weth-wei", "1500000000000000000000", 1), balancerAsset("uosmo", "2000000000000", 1)),
+			cosmosSdk.NewCoin("uosmo", amount("5000000000")), "weth-wei",
+		},
+		{
+			"stableswap",
+			newStableswapPool(t, 5, "0.001", cosmosSdk.NewCoins(cosmosSdk.NewCoin("uusdc", amount("800000000000")), cosmosSdk.NewCoin("uusdt", amount("750000000000"))), []uint64{1, 1}),
+			cosmosSdk.NewCoin("uusdc", amount("2000000000")), "uusdt",
+		},
+		{
+			"stableswap with scaling factors",
+			newStableswapPool(t, 6, "0.001", cosmosSdk.NewCoins(cosmosSdk.NewCoin("dai-wei", amount("500000000000000000000000")), cosmosSdk.NewCoin("uusdc", amount("520000000000"))), []uint64{1000000000000, 1}),
+			cosmosSdk.NewCoin("uusdc", amount("1000000000")), "dai-wei",
+		},
+		{
+			"stableswap with three assets",
+			newStableswapPool(t, 7, "0.0005", cosmosSdk.NewCoins(cosmosSdk.NewCoin("udai", amount("300000000000")), cosmosSdk.NewCoin("uusdc", amount("310000000000")), cosmosSdk.NewCoin("uusdt", amount("290000000000"))), []uint64{1, 1, 1}),
+			cosmosSdk.NewCoin("uusdt", amount("500000000")), "udai",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			expected, err := c.pool.CalcOutAmtGivenIn(cosmosSdk.Context{}, cosmosSdk.NewCoins(c.tokenIn), c.denomOut, c.pool.GetSwapFee(cosmosSdk.Context{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pool := poolStates(t, c.pool)[c.pool.GetId()]
+			actual, err := pool.CalcOutGivenIn(c.tokenIn.Denom, c.denomOut, float64(c.tokenIn.Amount.Int64()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectedOut, _ := expected.Amount.ToDec().Float64()
+			if !withinTolerance(math.Floor(actual), expectedOut, osmosisTolerance) {
+				t.Errorf("simulated %.0f%s, Osmosis returns %s", actual, c.denomOut, expected)
+			}
+		})
+	}
+}
+
+func TestCalcOutGivenInLimits(t *testing.T) {
+	pools := poolStates(t, newBalancerPool(t, 1, "0.002", balancerAsset("uatom", "1000000000", 1), balancerAsset("uosmo", "10000000000", 1)))
+	empty := &simulator.PoolState{
+		PoolId:   2,
+		PoolType: simulator.PoolTypeBalancer,
+		SwapFee:  cosmosSdk.MustNewDecFromStr("0.002"),
+		Assets: map[string]simulator.PoolAsset{
+			"uatom": {Reserve: cosmosSdk.ZeroInt(), Weight: cosmosSdk.NewInt(1)},
+			"uosmo": {Reserve: cosmosSdk.NewInt(10000000000), Weight: cosmosSdk.NewInt(1)},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		pool     *simulator.PoolState
+		denomIn  string
+		denomOut string
+		amountIn float64
+		valid    bool
+	}{
+		{"at the maximum reserve ratio", pools[1], "uatom", "uosmo", 1000000000 * simulator.MaxReserveRatio, true},
+		{"above the maximum reserve ratio", pools[1], "uatom", "uosmo", 1000000000*simulator.MaxReserveRatio + 1, false},
+		{"denom not in the pool", pools[1], "uion", "uosmo", 1000, false},
+		{"empty reserve in", empty, "uatom", "uosmo", 1000, false},
+		{"empty reserve out", empty, "uosmo", "uatom", 1000, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := c.pool.CalcOutGivenIn(c.denomIn, c.denomOut, c.amountIn)
+			if c.valid && err != nil {
+				t.Errorf("expected a valid swap, got %s", err.Error())
+			} else if !c.valid && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestApplySwapLargeAmounts(t *testing.T) {
+	pools := poolStates(t, newBalancerPool(t, 1, "0.002", balancerAsset("weth-wei", "1500000000000000000000", 1), balancerAsset("uosmo", "2000000000000", 1)))
+	pool := pools[1]
+
+	//More wei than fits in an int64
+	amountIn := 20000000000000000000.0
+	amountOut, err := pool.ApplySwap("weth-wei", "uosmo", amountIn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reserve := pool.Assets["weth-wei"].Reserve; !reserve.Equal(amount("1520000000000000000000")) {
+		t.Errorf("weth-wei reserve is %s, expected 1520000000000000000000", reserve)
+	}
+	expectedOsmo := cosmosSdk.NewInt(2000000000000).Sub(cosmosSdk.NewInt(int64(amountOut)))
+	if reserve := pool.Assets["uosmo"].Reserve; !reserve.Equal(expectedOsmo) {
+		t.Errorf("uosmo reserve is %s, expected %s", reserve, expectedOsmo)
+	}
+}
+
+func TestIntFromFloat(t *testing.T) {
+	cases := []struct {
+		amount   float64
+		expected string //Empty if the amount is invalid
+	}{
+		{0, "0"},
+		{1234.99, "1234"},
+		{1e30, "1000000000000000019884624838656"},
+		{-1, ""},
+		{math.NaN(), ""},
+		{math.Inf(1), ""},
+		{1e80, ""},
+	}
+
+	for _, c := range cases {
+		i, err := simulator.IntFromFloat(c.amount)
+		if c.expected == "" {
+			if err == nil {
+				t.Errorf("expected an error for %g, got %s", c.amount, i)
+			}
+		} else if err != nil {
+			t.Errorf("unexpected error for %g: %s", c.amount, err.Error())
+		} else if i.String() != c.expected {
+			t.Errorf("%g converted to %s, expected %s", c.amount, i, c.expected)
+		}
+	}
+}
+
+func TestVerifySimulation(t *testing.T) {
+	osmoAtom := newBalancerPool(t, 1, "0.002", balancerAsset("uatom", "250000000000", 1), balancerAsset("uosmo", "2500000000000", 1))
+	atomOsmo := newBalancerPool(t, 2, "0.002", balancerAsset("uatom", "260000000000", 1), balancerAsset("uosmo", "2500000000000", 1))
+
+	userIn := cosmosSdk.NewCoin("uosmo", amount("20000000000"))
+	userOut, err := osmoAtom.CalcOutAmtGivenIn(cosmosSdk.Context{}, cosmosSdk.NewCoins(userIn), "uatom", osmoAtom.GetSwapFee(cosmosSdk.Context{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newResult := func(arbAmountOut cosmosSdk.Int) *simulator.SimulatedSwapResult {
+		result := &simulator.SimulatedSwapResult{
+			SimulatedUserSwap: &simulator.SimulatedSwap{
+				TokenIn:        userIn,
+				Routes:         gammTypes.SwapAmountInRoutes{{PoolId: 1, TokenOutDenom: "uatom"}},
+				TokenOutAmount: userOut.Amount,
+				TokenOutDenom:  "uatom",
+			},
+		}
+		result.SetArbitrageSwaps([]*simulator.ArbitrageSwap{{
+			SimulatedSwap: &simulator.SimulatedSwap{
+				TokenIn:        cosmosSdk.NewCoin("uosmo", amount("1000000000")),
+				Routes:         gammTypes.SwapAmountInRoutes{{PoolId: 2, TokenOutDenom: "uatom"}, {PoolId: 1, TokenOutDenom: "uosmo"}},
+				TokenOutAmount: arbAmountOut,
+				TokenOutDenom:  "uosmo",
+			},
+			EstimatedProfitHumanReadable: "1000000000uosmo",
+			EstimatedProfitBaseAmount:    "1000000000",
+		}})
+		return result
+	}
+
+	//Find the arbitrage amount out the same way the chain would: after the user's swap
+	pools := poolStates(t, osmoAtom, atomOsmo)
+	err = pools.ApplyRoute([]simulator.Hop{{PoolId: 1, DenomIn: "uosmo", DenomOut: "uatom"}}, float64(userIn.Amount.Int64()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	arbHops := []simulator.Hop{{PoolId: 2, DenomIn: "uosmo", DenomOut: "uatom"}, {PoolId: 1, DenomIn: "uatom", DenomOut: "uosmo"}}
+	arbOut, err := pools.SimulateRoute(arbHops, 1000000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedArbOut := cosmosSdk.NewInt(int64(arbOut))
+	expectedProfit := expectedArbOut.Sub(cosmosSdk.NewInt(1000000000))
+
+	t.Run("within tolerance", func(t *testing.T) {
+		//The client overstated the arbitrage by .5%
+		result := newResult(expectedArbOut.MulRaw(1005).QuoRaw(1000))
+		err := simulator.VerifySimulation(poolStates(t, osmoAtom, atomOsmo), result, 0.01)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		arbSwap := result.GetArbitrageSwaps()[0]
+		if !arbSwap.SimulatedSwap.TokenOutAmount.Equal(expectedArbOut) {
+			t.Errorf("arbitrage amount out is %s, expected %s", arbSwap.SimulatedSwap.TokenOutAmount, expectedArbOut)
+		}
+		if arbSwap.EstimatedProfitBaseAmount != expectedProfit.String() {
+			t.Errorf("estimated profit is %s, expected %s", arbSwap.EstimatedProfitBaseAmount, expectedProfit)
+		}
+		if arbSwap.EstimatedProfitHumanReadable != expectedProfit.String()+"uosmo" {
+			t.Errorf("estimated profit is %s, expected %suosmo", arbSwap.EstimatedProfitHumanReadable, expectedProfit)
+		}
+	})
+
+	t.Run("outside tolerance", func(t *testing.T) {
+		result := newResult(expectedArbOut.MulRaw(11).QuoRaw(10))
+		if simulator.VerifySimulation(poolStates(t, osmoAtom, atomOsmo), result, 0.01) == nil {
+			t.Error("expected the overstated arbitrage to be rejected")
+		}
+	})
+
+	t.Run("exact amount out", func(t *testing.T) {
+		//The user allows twice the amount in, but the arbitrage trades against the pools moved by the amount actually spent
+		result := newResult(expectedArbOut)
+		result.SimulatedUserSwap = &simulator.SimulatedSwap{
+			TokenOut:         userOut,
+			TokenInMaxAmount: userIn.Amount.MulRaw(2),
+			OutRoutes:        gammTypes.SwapAmountOutRoutes{{PoolId: 1, TokenInDenom: "uosmo"}},
+		}
+		err := simulator.VerifySimulation(poolStates(t, osmoAtom, atomOsmo), result, 0.01)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		arbAmountOut := result.GetArbitrageSwaps()[0].SimulatedSwap.TokenOutAmount
+		if !withinTolerance(float64(arbAmountOut.Int64()), float64(expectedArbOut.Int64()), osmosisTolerance) {
+			t.Errorf("arbitrage amount out is %s, expected %s", arbAmountOut, expectedArbOut)
+		}
+	})
+}
+
+func TestPriceImpact(t *testing.T) {
+	a1, b1 := 2500000000000.0, 250000000000.0 //Pool 1 uosmo, uatom
+	a2 := 250000000000.0                      //Pool 2 uatom
+	pools := poolStates(t,
+		newBalancerPool(t, 1, "0.002", balancerAsset("uosmo", "2500000000000", 1), balancerAsset("uatom", "250000000000", 1)),
+		newBalancerPool(t, 2, "0.002", balancerAsset("uatom", "250000000000", 1), balancerAsset("uusdc", "1250000000000", 1)),
+	)
+	oneHop := []simulator.Hop{{PoolId: 1, DenomIn: "uosmo", DenomOut: "uatom"}}
+	twoHops := []simulator.Hop{{PoolId: 1, DenomIn: "uosmo", DenomOut: "uatom"}, {PoolId: 2, DenomIn: "uatom", DenomOut: "uusdc"}}
+
+	//A 50/50 pool pays B*g*x/(A + g*x) at a marginal rate of B*g/A, so the price impact is g*x/(A + g*x)
+	g := 1 - 0.002
+	oneHopImpact := func(x float64) float64 {
+		return g * x / (a1 + g*x)
+	}
+	twoHopImpact := func(x float64) float64 {
+		atomOut := b1 * g * x / (a1 + g*x)
+		return 1 - (a1/(a1+g*x))*(a2/(a2+g*atomOut))
+	}
+
+	cases := []struct {
+		name     string
+		hops     []simulator.Hop
+		amountIn float64
+		expected float64
+	}{
+		{"tiny swap", oneHop, 1000, oneHopImpact(1000)},
+		{"one hop", oneHop, 10000000000, oneHopImpact(10000000000)},
+		{"large one hop", oneHop, 500000000000, oneHopImpact(500000000000)},
+		{"two hops", twoHops, 10000000000, twoHopImpact(10000000000)},
+		{"large two hops", twoHops, 500000000000, twoHopImpact(500000000000)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			amountOut, priceImpact, err := pools.PriceImpact(c.hops, c.amountIn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			//Tiny impacts are within rounding of zero, so they are compared with an absolute tolerance
+			if math.Abs(priceImpact-c.expected) > 1e-9 && !withinTolerance(priceImpact, c.expected, osmosisTolerance) {
+				t.Errorf("price impact is %g, expected %g", priceImpact, c.expected)
+			}
+
+			expectedOut, err := pools.SimulateRoute(c.hops, c.amountIn)
+			if err != nil {
+				t.Fatal(err)
+			} else if amountOut != expectedOut {
+				t.Errorf("amount out is %f, the route pays %f", amountOut, expectedOut)
+			}
+		})
+	}
+
+	t.Run("larger swaps move the price more", func(t *testing.T) {
+		_, small, _ := pools.PriceImpact(twoHops, 10000000000)
+		_, large, _ := pools.PriceImpact(twoHops, 500000000000)
+		if small >= large {
+			t.Errorf("price impact of %g for the small swap, %g for the large swap", small, large)
+		}
+	})
+
+	t.Run("missing pool", func(t *testing.T) {
+		_, _, err := pools.PriceImpact([]simulator.Hop{{PoolId: 3, DenomIn: "uosmo", DenomOut: "uatom"}}, 1000)
+		if err == nil {
+			t.Error("expected an error for a pool without data")
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	cosmosTypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+// User swaps trade through at most this many pools
+const MaxRouteHops = 4
+
+var ErrNoRoute = errors.New("no route found")
+
+// Finds the route (up to maxHops pools, at most MaxRouteHops) that returns the most denomOut for tokenIn.
+// The returned swap has the simulated amount out and price impact filled in, but no minimum amount out.
+func FindBestRoute(pools PoolStates, tokenIn cosmosTypes.Coin, denomOut string, maxHops int) (*SimulatedSwap, error) {
+	amountIn := toFloat(tokenIn.Amount.ToDec())
+	if amountIn <= 0 {
+		return nil, errors.New("amount in must be positive")
+	} else if tokenIn.Denom == denomOut {
+		return nil, errors.New("token in and token out must be different denoms")
+	}
+
+	if maxHops <= 0 || maxHops > MaxRouteHops {
+		maxHops = MaxRouteHops
+	}
+
+	graph := buildPoolGraph(pools)
+	var bestHops []Hop
+	bestOut := 0.0
+	steps := 0
+
+	var search func(path []Hop, current string, visited map[string]bool, used map[uint64]bool)
+	search = func(path []Hop, current string, visited map[string]bool, used map[uint64]bool) {
+		for _, edge := range graph[current] {
+			steps++
+			if steps > maxSearchSteps {
+				return
+			} else if used[edge.poolId] || visited[edge.denomOut] {
+				continue
+			}
+
+			hop := Hop{PoolId: edge.poolId, DenomIn: current, DenomOut: edge.denomOut}
+			nextPath := append(append([]Hop{}, path...), hop)
+
+			if edge.denomOut == denomOut {
+				out, err := pools.SimulateRoute(nextPath, amountIn)
+				if err == nil && out > bestOut {
+					bestHops, bestOut = nextPath, out
+				}
+				continue
+			}
+
+			if len(nextPath) < maxHops {
+				used[edge.poolId] = true
+				visited[edge.denomOut] = true
+				search(nextPath, edge.denomOut, visited, used)
+				delete(used, edge.poolId)
+				delete(visited, edge.denomOut)
+			}
+		}
+	}
+	search([]Hop{}, tokenIn.Denom, map[string]bool{tokenIn.Denom: true}, map[uint64]bool{})
+
+	amountOut := math.Floor(bestOut)
+	if bestHops == nil || amountOut < 1 {
+		return nil, ErrNoRoute
+	}
+
+	poolIds := []string{}
+	for _, hop := range bestHops {
+		poolIds = append(poolIds, fmt.Sprint(hop.PoolId))
+	}
+
+	_, priceImpact, err := pools.PriceImpact(bestHops, amountIn)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenOutAmount, err := IntFromFloat(amountOut)
+	if err != nil {
+		return nil, err
+	}
+
+	swap := &SimulatedSwap{
+		TokenIn:        tokenIn,
+		Pools:          strings.Join(poolIds, ","),
+		TokenOutAmount: tokenOutAmount,
+		TokenOutDenom:  denomOut,
+		Routes:         RoutesFromHops(bestHops),
+		PriceImpact:    priceImpact,
+	}
+	swap.AmountOutHumanReadable = cosmosTypes.NewCoin(denomOut, swap.TokenOutAmount).String()
+	return swap, nil
+}
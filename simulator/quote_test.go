@@ -0,0 +1,72 @@
+package simulator_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DefiantLabs/RedpointSwap/simulator"
+	cosmosSdk "github.com/cosmos/cosmos-sdk/types"
+	gammTypes "github.com/osmosis-labs/osmosis/v13/x/gamm/types"
+)
+
+func TestFindBestRoute(t *testing.T) {
+	//The direct uosmo/uusdc pool is shallow, so large swaps get more uusdc through uatom
+	direct := newBalancerPool(t, 1, "0.002", balancerAsset("uosmo", "20000000000", 1), balancerAsset("uusdc", "10000000000", 1))
+	osmoAtom := newBalancerPool(t, 2, "0.002", balancerAsset("uosmo", "2500000000000", 1), balancerAsset("uatom", "250000000000", 1))
+	atomUsdc := newBalancerPool(t, 3, "0.002", balancerAsset("uatom", "250000000000", 1), balancerAsset("uusdc", "1250000000000", 1))
+	pools := poolStates(t, direct, osmoAtom, atomUsdc)
+
+	cases := []struct {
+		name          string
+		tokenIn       cosmosSdk.Coin
+		maxHops       int
+		expectedPools string
+	}{
+		{"small swap", cosmosSdk.NewCoin("uosmo", amount("1000")), 0, "1"},
+		{"large swap", cosmosSdk.NewCoin("uosmo", amount("2000000000")), 0, "2,3"},
+		{"large swap limited to one hop", cosmosSdk.NewCoin("uosmo", amount("2000000000")), 1, "1"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			swap, err := simulator.FindBestRoute(pools, c.tokenIn, "uusdc", c.maxHops)
+			if err != nil {
+				t.Fatal(err)
+			} else if swap.Pools != c.expectedPools {
+				t.Fatalf("routed through pools %s, expected %s", swap.Pools, c.expectedPools)
+			}
+
+			//The amount out should match Osmosis trading through the same pools
+			expected := c.tokenIn
+			for _, route := range swap.Routes {
+				pool := map[uint64]gammTypes.PoolI{1: direct, 2: osmoAtom, 3: atomUsdc}[route.PoolId]
+				expected, err = pool.CalcOutAmtGivenIn(cosmosSdk.Context{}, cosmosSdk.NewCoins(expected), route.TokenOutDenom, pool.GetSwapFee(cosmosSdk.Context{}))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expectedOut, _ := expected.Amount.ToDec().Float64()
+			if !withinTolerance(float64(swap.TokenOutAmount.Int64()), expectedOut, osmosisTolerance) {
+				t.Errorf("amount out is %s, Osmosis returns %s", swap.TokenOutAmount, expected)
+			}
+			if swap.PriceImpact < 0 || swap.PriceImpact >= 1 {
+				t.Errorf("price impact %f is out of range", swap.PriceImpact)
+			}
+		})
+	}
+
+	t.Run("no route", func(t *testing.T) {
+		_, err := simulator.FindBestRoute(pools, cosmosSdk.NewCoin("uosmo", amount("1000")), "uion", 0)
+		if !errors.Is(err, simulator.ErrNoRoute) {
+			t.Errorf("expected ErrNoRoute, got %v", err)
+		}
+	})
+
+	t.Run("same denom", func(t *testing.T) {
+		_, err := simulator.FindBestRoute(pools, cosmosSdk.NewCoin("uosmo", amount("1000")), "uosmo", 0)
+		if err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	cosmosTypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/osmosis-labs/osmosis/v13/x/gamm/types"
+)
+
+// Arbitrage cycles are between 2 and 5 pools long (longer cycles cost more gas than they usually earn)
+const (
+	MinCycleHops = 2
+	MaxCycleHops = 5
+)
+
+var ErrNoArbitrage = errors.New("no profitable arbitrage cycle found")
+
+// Upper bounds on the work per search, so a large pool graph can't stall a request (searches run in request handlers
+// and on every mempool poll). Only the candidate cycles with the best marginal rates are sized, since sizing dominates the cost.
+const (
+	maxCycleCandidates = 500
+	maxSizedCycles     = 20
+	maxSearchSteps     = 50000
+)
+
+type cycleCandidate struct {
+	hops []Hop
+	rate float64 //Product of the marginal rates, the profit per token for a tiny trade is rate - 1
+}
+
+// An arbitrage cycle through the pools, sized to the most profitable amount in
+type ArbitrageCycle struct {
+	Hops      []Hop
+	AmountIn  cosmosTypes.Int
+	AmountOut cosmosTypes.Int
+}
+
+func (cycle *ArbitrageCycle) Denom() string {
+	return cycle.Hops[0].DenomIn
+}
+
+func (cycle *ArbitrageCycle) Profit() cosmosTypes.Int {
+	return cycle.AmountOut.Sub(cycle.AmountIn)
+}
+
+func (cycle *ArbitrageCycle) Routes() types.SwapAmountInRoutes {
+	return RoutesFromHops(cycle.Hops)
+}
+
+// The arbitrage swap for this cycle. The minimum amount out is the amount in, so the hot wallet can't lose funds (excluding fees).
+func (cycle *ArbitrageCycle) ToArbitrageSwap() *ArbitrageSwap {
+	poolIds := []string{}
+	for _, hop := range cycle.Hops {
+		poolIds = append(poolIds, fmt.Sprint(hop.PoolId))
+	}
+
+	return &ArbitrageSwap{
+		SimulatedSwap: &SimulatedSwap{
+			TokenIn:           cosmosTypes.NewCoin(cycle.Denom(), cycle.AmountIn),
+			TokenOutMinAmount: cycle.AmountIn,
+			Pools:             strings.Join(poolIds, ","),
+			Routes:            cycle.Routes(),
+			TokenOutAmount:    cycle.AmountOut,
+			TokenOutDenom:     cycle.Denom(),
+		},
+		EstimatedProfitHumanReadable: cosmosTypes.NewCoin(cycle.Denom(), cycle.Profit()).String(),
+		EstimatedProfitBaseAmount:    cycle.Profit().String(),
+	}
+}
+
+type poolEdge struct {
+	poolId   uint64
+	denomOut string
+	rate     float64 //Marginal exchange rate (after swap fee) for a small trade
+}
+
+// Finds the most profitable arbitrage cycle that starts and ends in denom and trades through at least one of the touched pools
+// (e.g. the pools the user's swap moves). The pools should already reflect the user's swap. Cycles have at most maxHops pools (up to MaxCycleHops).
+func FindBestArbitrage(pools PoolStates, denom string, maxAmountIn cosmosTypes.Int, touchedPools []uint64, maxHops int) (*ArbitrageCycle, error) {
+	if !maxAmountIn.IsPositive() {
+		return nil, fmt.Errorf("no %s available for arbitrage", denom)
+	}
+
+	if maxHops <= 0 || maxHops > MaxCycleHops {
+		maxHops = MaxCycleHops
+	}
+
+	touched := map[uint64]bool{}
+	for _, poolId := range touchedPools {
+		touched[poolId] = true
+	}
+
+	graph := buildPoolGraph(pools)
+	candidates := []cycleCandidate{}
+	found := map[string]bool{}
+	steps := 0
+
+	addCandidate := func(hops []Hop, rate float64) {
+		//Rotate the cycle so it starts (and ends) in denom
+		for i, hop := range hops {
+			if hop.DenomIn == denom {
+				hops = append(append([]Hop{}, hops[i:]...), hops[:i]...)
+				break
+			}
+		}
+
+		key := fmt.Sprint(hops)
+		if len(hops) >= MinCycleHops && !found[key] {
+			found[key] = true
+			candidates = append(candidates, cycleCandidate{hops: hops, rate: rate})
+		}
+	}
+
+	//Depth first search for paths back to start that trade through denom exactly once,
+	//only keeping cycles whose marginal rates multiply to more than 1
+	var search func(path []Hop, start string, current string, rate float64, used map[uint64]bool, throughDenom bool)
+	search = func(path []Hop, start string, current string, rate float64, used map[uint64]bool, throughDenom bool) {
+		for _, edge := range graph[current] {
+			steps++
+			if len(candidates) >= maxCycleCandidates || steps > maxSearchSteps {
+				return
+			} else if used[edge.poolId] || (throughDenom && edge.denomOut == denom && start != denom) {
+				continue
+			}
+
+			hop := Hop{PoolId: edge.poolId, DenomIn: current, DenomOut: edge.denomOut}
+			nextPath := append(append([]Hop{}, path...), hop)
+			nextRate := rate * edge.rate
+			nextThroughDenom := throughDenom || edge.denomOut == denom
+
+			if edge.denomOut == start && nextThroughDenom {
+				if nextRate > 1 {
+					addCandidate(nextPath, nextRate)
+				}
+				continue
+			}
+
+			if len(nextPath) < maxHops {
+				used[edge.poolId] = true
+				search(nextPath, start, edge.denomOut, nextRate, used, nextThroughDenom)
+				delete(used, edge.poolId)
+			}
+		}
+	}
+
+	if len(touched) == 0 {
+		search([]Hop{}, denom, denom, 1, map[uint64]bool{}, true)
+	} else {
+		//Seed the search with every direction of every touched pool, so the search budget is only spent on cycles through them
+		denoms := []string{}
+		for denomIn := range graph {
+			denoms = append(denoms, denomIn)
+		}
+		sort.Strings(denoms)
+
+		for _, denomIn := range denoms {
+			for _, edge := range graph[denomIn] {
+				if !touched[edge.poolId] {
+					continue
+				}
+
+				seed := []Hop{{PoolId: edge.poolId, DenomIn: denomIn, DenomOut: edge.denomOut}}
+				search(seed, denomIn, edge.denomOut, edge.rate, map[uint64]bool{edge.poolId: true}, denomIn == denom || edge.denomOut == denom)
+			}
+		}
+	}
+
+	//Stable, so cycles with the same rate are sized in the order they were found
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].rate > candidates[j].rate })
+	if len(candidates) > maxSizedCycles {
+		candidates = candidates[:maxSizedCycles]
+	}
+
+	var best *ArbitrageCycle
+	for _, candidate := range candidates {
+		hops := candidate.hops
+		amountIn, amountOut, err := OptimalArbitrageAmount(pools, hops, maxAmountIn)
+		if err != nil {
+			continue
+		}
+
+		cycle := &ArbitrageCycle{Hops: hops, AmountIn: amountIn, AmountOut: amountOut}
+		if best == nil || cycle.Profit().GT(best.Profit()) {
+			best = cycle
+		}
+	}
+
+	if best == nil {
+		return nil, ErrNoArbitrage
+	}
+	return best, nil
+}
+
+// Edges from each denom to every denom it can be swapped for. Key: denom in
+func buildPoolGraph(pools PoolStates) map[string][]poolEdge {
+	graph := map[string][]poolEdge{}
+
+	//Sorted so searches are deterministic
+	poolIds := []uint64{}
+	for poolId := range pools {
+		poolIds = append(poolIds, poolId)
+	}
+	sort.Slice(poolIds, func(i, j int) bool { return poolIds[i] < poolIds[j] })
+
+	for _, poolId := range poolIds {
+		pool := pools[poolId]
+		denoms := []string{}
+		for denom := range pool.Assets {
+			denoms = append(denoms, denom)
+		}
+		sort.Strings(denoms)
+
+		for _, denomIn := range denoms {
+			for _, denomOut := range denoms {
+				if denomIn == denomOut {
+					continue
+				}
+
+				rate, err := pool.marginalRate(denomIn, denomOut)
+				if err != nil || rate <= 0 {
+					continue
+				}
+				graph[denomIn] = append(graph[denomIn], poolEdge{poolId: poolId, denomOut: denomOut, rate: rate})
+			}
+		}
+	}
+
+	return graph
+}
+
+// Exchange rate for a trade that is tiny compared to the pool reserves (includes the swap fee)
+func (pool *PoolState) marginalRate(denomIn string, denomOut string) (float64, error) {
+	reserveIn := toFloat(pool.Assets[denomIn].Reserve.ToDec())
+	amountIn := reserveIn / 1000000
+	if amountIn < 1 {
+		return 0, fmt.Errorf("pool %d has no liquidity for %s", pool.PoolId, denomIn)
+	}
+
+	amountOut, err := pool.CalcOutGivenIn(denomIn, denomOut, amountIn)
+	if err != nil {
+		return 0, err
+	}
+	return amountOut / amountIn, nil
+}
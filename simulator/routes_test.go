@@ -0,0 +1,135 @@
+package simulator_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/DefiantLabs/RedpointSwap/simulator"
+	cosmosSdk "github.com/cosmos/cosmos-sdk/types"
+	gammTypes "github.com/osmosis-labs/osmosis/v13/x/gamm/types"
+)
+
+func cycleUsesPool(cycle *simulator.ArbitrageCycle, poolId uint64) bool {
+	for _, hop := range cycle.Hops {
+		if hop.PoolId == poolId {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFindBestArbitrage(t *testing.T) {
+	//Pools 1 and 2 disagree on the uatom price, pools 3 and 4 disagree much more on the uusdc price
+	pools := poolStates(t,
+		newBalancerPool(t, 1, "0.002", balancerAsset("uosmo", "2500000000000", 1), balancerAsset("uatom", "250000000000", 1)),
+		newBalancerPool(t, 2, "0.002", balancerAsset("uosmo", "2600000000000", 1), balancerAsset("uatom", "240000000000", 1)),
+		newBalancerPool(t, 3, "0.002", balancerAsset("uosmo", "2000000000000", 1), balancerAsset("uusdc", "1000000000000", 1)),
+		newBalancerPool(t, 4, "0.002", balancerAsset("uosmo", "2000000000000", 1), balancerAsset("uusdc", "1200000000000", 1)),
+	)
+	maxAmountIn := cosmosSdk.NewInt(1000000000000)
+
+	t.Run("any pool", func(t *testing.T) {
+		cycle, err := simulator.FindBestArbitrage(pools, "uosmo", maxAmountIn, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		} else if !cycleUsesPool(cycle, 3) || !cycleUsesPool(cycle, 4) {
+			t.Errorf("expected the uusdc cycle, got %v", cycle.Hops)
+		}
+	})
+
+	t.Run("through a touched pool", func(t *testing.T) {
+		cycle, err := simulator.FindBestArbitrage(pools, "uosmo", maxAmountIn, []uint64{1}, 0)
+		if err != nil {
+			t.Fatal(err)
+		} else if !cycleUsesPool(cycle, 1) {
+			t.Errorf("cycle %v doesn't trade through touched pool 1", cycle.Hops)
+		} else if cycle.Denom() != "uosmo" || cycle.Hops[len(cycle.Hops)-1].DenomOut != "uosmo" {
+			t.Errorf("cycle %v doesn't start and end in uosmo", cycle.Hops)
+		} else if !cycle.Profit().IsPositive() {
+			t.Errorf("cycle %v isn't profitable", cycle.Hops)
+		}
+	})
+
+	t.Run("touched pool without arbitrage", func(t *testing.T) {
+		withUnrelated := poolStates(t, newBalancerPool(t, 5, "0.002", balancerAsset("uion", "1000000000", 1), balancerAsset("uosmo", "400000000000", 1)))
+		for poolId, pool := range pools {
+			withUnrelated[poolId] = pool
+		}
+
+		_, err := simulator.FindBestArbitrage(withUnrelated, "uosmo", maxAmountIn, []uint64{5}, 0)
+		if !errors.Is(err, simulator.ErrNoArbitrage) {
+			t.Errorf("expected ErrNoArbitrage, got %v", err)
+		}
+	})
+}
+
+func TestFindBestArbitrageLargeGraph(t *testing.T) {
+	//Dozens of fairly priced pools per pair (searched first, they have lower IDs) would use up the search budget
+	//before an unseeded search reaches the touched pool
+	osmoPools := []gammTypes.PoolI{}
+	poolId := uint64(1)
+	for _, pair := range [][2]string{{"uatom", "uosmo"}, {"uosmo", "uusdc"}, {"uatom", "uusdc"}} {
+		for i := 0; i < 40; i++ {
+			reserves := map[string]string{"uosmo": "2500000000000", "uatom": "250000000000", "uusdc": "2500000000000"}
+			osmoPools = append(osmoPools, newBalancerPool(t, poolId, "0.002", balancerAsset(pair[0], reserves[pair[0]], 1), balancerAsset(pair[1], reserves[pair[1]], 1)))
+			poolId++
+		}
+	}
+	osmoPools = append(osmoPools,
+		newBalancerPool(t, 1000, "0.002", balancerAsset("uosmo", "2500000000000", 1), balancerAsset("uatom", "250000000000", 1)),
+		newBalancerPool(t, 1001, "0.002", balancerAsset("uosmo", "2600000000000", 1), balancerAsset("uatom", "240000000000", 1)),
+	)
+	pools := poolStates(t, osmoPools...)
+
+	cycle, err := simulator.FindBestArbitrage(pools, "uosmo", cosmosSdk.NewInt(1000000000000), []uint64{1000}, 3)
+	if err != nil {
+		t.Fatal(err)
+	} else if !cycleUsesPool(cycle, 1000) {
+		t.Errorf("cycle %v doesn't trade through touched pool 1000", cycle.Hops)
+	} else if !cycleUsesPool(cycle, 1001) {
+		t.Errorf("expected the cycle through pools 1000 and 1001, got %v", fmt.Sprint(cycle.Hops))
+	}
+}
+
+// Roughly the size of Osmosis' pool graph: several hundred balancer pools over a few dozen denoms, mostly paired with OSMO,
+// plus a few stableswap pools. Prices are slightly off so there are many profitable cycles to size.
+func BenchmarkFindBestArbitrage(b *testing.B) {
+	random := rand.New(rand.NewSource(1))
+	denoms := []string{"uosmo"}
+	for i := 0; i < 40; i++ {
+		denoms = append(denoms, fmt.Sprintf("ibc/%d", i))
+	}
+
+	osmoPools := []gammTypes.PoolI{}
+	poolId := uint64(1)
+	reserve := func() string {
+		return fmt.Sprint(int64(1000000000000 * (1 + 0.02*random.Float64())))
+	}
+	for i := 0; i < 600; i++ {
+		denomIn, denomOut := "uosmo", denoms[1+random.Intn(len(denoms)-1)]
+		if i%3 == 0 {
+			denomIn = denoms[1+random.Intn(len(denoms)-1)]
+		}
+		if denomIn == denomOut {
+			continue
+		}
+		osmoPools = append(osmoPools, newBalancerPool(b, poolId, "0.002", balancerAsset(denomIn, reserve(), 1), balancerAsset(denomOut, reserve(), 1)))
+		poolId++
+	}
+	for i := 1; i <= 10; i++ {
+		liquidity := cosmosSdk.NewCoins(cosmosSdk.NewCoin("uosmo", amount(reserve())), cosmosSdk.NewCoin(denoms[i], amount(reserve())))
+		osmoPools = append(osmoPools, newStableswapPool(b, poolId, "0.001", liquidity, []uint64{1, 1}))
+		poolId++
+	}
+	pools := poolStates(b, osmoPools...)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := simulator.FindBestArbitrage(pools, "uosmo", cosmosSdk.NewInt(1000000000000), []uint64{1, 2, 3}, 3)
+		if err != nil && !errors.Is(err, simulator.ErrNoArbitrage) {
+			b.Fatal(err)
+		}
+	}
+}
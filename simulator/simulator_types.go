@@ -12,12 +12,16 @@ type SimulatedSwapResult struct {
 	SimulatedUserSwap *SimulatedSwap `json:"userSwap,omitempty"`
 	// how much arbitrage the user's swap will cause, routes to use, etc
 	ArbitrageSwap *ArbitrageSwap `json:"arbitrageSwap,omitempty"`
+	// every arbitrage cycle the user's swap causes, in the order they will be executed (takes precedence over ArbitrageSwap)
+	ArbitrageSwaps []*ArbitrageSwap `json:"arbitrageSwaps,omitempty"`
 	// Whether or not the user's swap will cause arbitrage once executed on chain
 	HasArbitrageOpportunity bool
 	// if there was some issue detected on the server
 	Error string `json:"error,omitempty"`
 	// Address of the user whose tokens will be swapped
 	UserAddress string //e.g. osmo14tkd4079rnk7vnt0q9pg3pj44eyz8ahqrtajln
+	// Chain height of the pool state the amounts were simulated with (0 if unknown)
+	SimulationHeight int64 `json:"simulationHeight,omitempty"`
 }
 
 type ArbitrageSwap struct {
@@ -58,4 +62,106 @@ type SimulatedSwap struct {
 	// Amount this trade impacts the pool prices. For example, .025 would mean a 2.5% impact.
 	// example: .025
 	PriceImpact float64
+	// Only for MsgSwapExactAmountOut trades. Will be the exact amount/denomination to receive on-chain for the trade
+	TokenOut cosmosTypes.Coin
+	// Only for MsgSwapExactAmountOut trades. Will be the exact amount to submit on-chain as the maximum amount in for the trade
+	TokenInMaxAmount cosmosTypes.Int
+	// Only for MsgSwapExactAmountOut trades. The exact routes to use for the trade, in the order they will be traded through.
+	// example: [{"pool_id":1,"token_in_denom":"uosmo"}]
+	OutRoutes types.SwapAmountOutRoutes `json:"outRoutes,omitempty"`
+}
+
+// Whether this swap must be submitted as a MsgSwapExactAmountOut (otherwise MsgSwapExactAmountIn)
+func (swap *SimulatedSwap) IsExactAmountOut() bool {
+	return len(swap.OutRoutes) > 0
+}
+
+// The token (and amount) the swap will spend. For MsgSwapExactAmountOut this is the maximum amount in.
+func (swap *SimulatedSwap) GetTokenIn() cosmosTypes.Coin {
+	if swap.IsExactAmountOut() {
+		amount := swap.TokenInMaxAmount
+		if amount.IsNil() {
+			amount = cosmosTypes.ZeroInt()
+		}
+		return cosmosTypes.Coin{Denom: swap.OutRoutes[0].TokenInDenom, Amount: amount}
+	}
+
+	return swap.TokenIn
+}
+
+// The token (and amount) the swap is expected to receive
+func (swap *SimulatedSwap) GetTokenOut() cosmosTypes.Coin {
+	if swap.IsExactAmountOut() {
+		return swap.TokenOut
+	}
+
+	amount := swap.TokenOutAmount
+	if amount.IsNil() {
+		amount = cosmosTypes.ZeroInt()
+	}
+	return cosmosTypes.Coin{Denom: swap.TokenOutDenom, Amount: amount}
+}
+
+// Number of pools the swap trades through
+func (swap *SimulatedSwap) NumRoutes() int {
+	if swap.IsExactAmountOut() {
+		return len(swap.OutRoutes)
+	}
+
+	return len(swap.Routes)
+}
+
+// The pools the swap trades through, in order
+func (swap *SimulatedSwap) PoolIds() []uint64 {
+	if swap.IsExactAmountOut() {
+		return swap.OutRoutes.PoolIds()
+	}
+
+	return swap.Routes.PoolIds()
+}
+
+// Every arbitrage swap in the simulation, in the order they will be executed
+func (result *SimulatedSwapResult) GetArbitrageSwaps() []*ArbitrageSwap {
+	if result == nil || !result.HasArbitrageOpportunity {
+		return []*ArbitrageSwap{}
+	}
+
+	arbSwaps := result.ArbitrageSwaps
+	if len(arbSwaps) == 0 {
+		arbSwaps = []*ArbitrageSwap{result.ArbitrageSwap}
+	}
+
+	//Skip incomplete swaps so callers don't have to check
+	complete := []*ArbitrageSwap{}
+	for _, arbSwap := range arbSwaps {
+		if arbSwap != nil && arbSwap.SimulatedSwap != nil {
+			complete = append(complete, arbSwap)
+		}
+	}
+	return complete
+}
+
+// Replaces the simulation's arbitrage swaps. ArbitrageSwap is kept as the first swap for clients that only read one.
+func (result *SimulatedSwapResult) SetArbitrageSwaps(arbSwaps []*ArbitrageSwap) {
+	result.ArbitrageSwaps = arbSwaps
+	result.ArbitrageSwap = nil
+	if len(arbSwaps) > 0 {
+		result.ArbitrageSwap = arbSwaps[0]
+	}
+	result.HasArbitrageOpportunity = len(arbSwaps) > 0
+}
+
+// The pools traded through by the user swap and the arbitrage swaps
+func (result *SimulatedSwapResult) PoolIds() []uint64 {
+	poolIds := []uint64{}
+	if result == nil {
+		return poolIds
+	}
+	if result.SimulatedUserSwap != nil {
+		poolIds = append(poolIds, result.SimulatedUserSwap.PoolIds()...)
+	}
+	for _, arbSwap := range result.GetArbitrageSwaps() {
+		poolIds = append(poolIds, arbSwap.SimulatedSwap.PoolIds()...)
+	}
+	return poolIds
 }
@@ -0,0 +1,89 @@
+package simulator
+
+import (
+	"errors"
+	"math"
+
+	cosmosTypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+var ErrUnprofitableArbitrage = errors.New("arbitrage is not profitable at current pool reserves")
+
+// Number of search steps. Each step shrinks the search interval to 2/3 of its size, so 200 steps is precise to 1 token for any realistic balance.
+const sizingIterations = 200
+
+// Finds the amount in that maximizes (amount out - amount in) for an arbitrage cycle through the pools.
+// The amount in will never exceed maxAmountIn (e.g. the wallet balance) or the liquidity of any pool on the route.
+func OptimalArbitrageAmount(pools PoolStates, hops []Hop, maxAmountIn cosmosTypes.Int) (amountIn cosmosTypes.Int, amountOut cosmosTypes.Int, err error) {
+	if len(hops) == 0 {
+		return cosmosTypes.ZeroInt(), cosmosTypes.ZeroInt(), errors.New("no arbitrage routes")
+	} else if hops[0].DenomIn != hops[len(hops)-1].DenomOut {
+		return cosmosTypes.ZeroInt(), cosmosTypes.ZeroInt(), errors.New("arbitrage route must start and end with the same denom")
+	}
+
+	upper, err := maxRouteAmount(pools, hops, toFloat(maxAmountIn.ToDec()))
+	if err != nil {
+		return cosmosTypes.ZeroInt(), cosmosTypes.ZeroInt(), err
+	}
+
+	profit := func(in float64) float64 {
+		out, err := pools.SimulateRoute(hops, in)
+		if err != nil {
+			return math.Inf(-1)
+		}
+		return out - in
+	}
+
+	//The profit of a cycle through constant function market makers is concave in the amount in, so a ternary search finds the maximum
+	lower := 0.0
+	for i := 0; i < sizingIterations && upper-lower > 1; i++ {
+		m1 := lower + (upper-lower)/3
+		m2 := upper - (upper-lower)/3
+		if profit(m1) < profit(m2) {
+			lower = m1
+		} else {
+			upper = m2
+		}
+	}
+
+	best := math.Floor(lower)
+	out, err := pools.SimulateRoute(hops, best)
+	if err != nil {
+		return cosmosTypes.ZeroInt(), cosmosTypes.ZeroInt(), err
+	} else if best < 1 || math.Floor(out) <= best {
+		return cosmosTypes.ZeroInt(), cosmosTypes.ZeroInt(), ErrUnprofitableArbitrage
+	}
+
+	amountIn, err = IntFromFloat(best)
+	if err != nil {
+		return cosmosTypes.ZeroInt(), cosmosTypes.ZeroInt(), err
+	}
+	amountOut, err = IntFromFloat(out)
+	if err != nil {
+		return cosmosTypes.ZeroInt(), cosmosTypes.ZeroInt(), err
+	}
+	return amountIn, amountOut, nil
+}
+
+// Largest amount (up to maxAmountIn) that can be traded through the route without exceeding any pool's liquidity
+func maxRouteAmount(pools PoolStates, hops []Hop, maxAmountIn float64) (float64, error) {
+	if _, err := pools.SimulateRoute(hops, maxAmountIn); err == nil {
+		return maxAmountIn, nil
+	}
+
+	//Later hops only get larger as the amount in grows, so a binary search finds the largest valid amount
+	lower, upper := 0.0, maxAmountIn
+	for i := 0; i < sizingIterations && upper-lower > 1; i++ {
+		mid := (lower + upper) / 2
+		if _, err := pools.SimulateRoute(hops, mid); err == nil {
+			lower = mid
+		} else {
+			upper = mid
+		}
+	}
+
+	if lower < 1 {
+		return 0, errors.New("pools on the arbitrage route do not have enough liquidity")
+	}
+	return lower, nil
+}
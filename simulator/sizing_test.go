@@ -0,0 +1,85 @@
+package simulator_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/DefiantLabs/RedpointSwap/simulator"
+	cosmosSdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func TestOptimalArbitrageAmount(t *testing.T) {
+	//uosmo is cheaper in pool 2 than in pool 1
+	a1, b1 := 2500000000000.0, 250000000000.0 //Pool 1 uosmo, uatom
+	a2, b2 := 2600000000000.0, 240000000000.0 //Pool 2 uosmo, uatom
+	pools := poolStates(t,
+		newBalancerPool(t, 1, "0.002", balancerAsset("uosmo", "2500000000000", 1), balancerAsset("uatom", "250000000000", 1)),
+		newBalancerPool(t, 2, "0.002", balancerAsset("uosmo", "2600000000000", 1), balancerAsset("uatom", "240000000000", 1)),
+	)
+	hops := []simulator.Hop{{PoolId: 1, DenomIn: "uosmo", DenomOut: "uatom"}, {PoolId: 2, DenomIn: "uatom", DenomOut: "uosmo"}}
+
+	//For two 50/50 pools the cycle pays K*x/(C + D*x), so the profit is largest at x = (sqrt(K*C) - C) / D
+	g := 1 - 0.002
+	k := a2 * g * g * b1
+	c := a1 * b2
+	d := g * (b2 + g*b1)
+	optimal := (math.Sqrt(k*c) - c) / d
+
+	t.Run("optimal amount", func(t *testing.T) {
+		amountIn, amountOut, err := simulator.OptimalArbitrageAmount(pools, hops, cosmosSdk.NewInt(1000000000000))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !withinTolerance(float64(amountIn.Int64()), optimal, 1e-4) {
+			t.Errorf("amount in is %s, expected about %.0f", amountIn, optimal)
+		}
+		expectedOut := k * float64(amountIn.Int64()) / (c + d*float64(amountIn.Int64()))
+		if !withinTolerance(float64(amountOut.Int64()), expectedOut, osmosisTolerance) {
+			t.Errorf("amount out is %s, expected about %.0f", amountOut, expectedOut)
+		}
+	})
+
+	t.Run("limited by the balance", func(t *testing.T) {
+		amountIn, _, err := simulator.OptimalArbitrageAmount(pools, hops, cosmosSdk.NewInt(1000000000))
+		if err != nil {
+			t.Fatal(err)
+		} else if amountIn.GT(cosmosSdk.NewInt(1000000000)) || amountIn.LT(cosmosSdk.NewInt(999999000)) {
+			t.Errorf("amount in is %s, expected the whole balance", amountIn)
+		}
+	})
+
+	t.Run("limited by the maximum reserve ratio", func(t *testing.T) {
+		//uatom is worth twice as much in pool 2, so the optimal amount is over 40% of pool 1's uosmo
+		shallow := poolStates(t,
+			newBalancerPool(t, 1, "0.002", balancerAsset("uosmo", "10000000000", 1), balancerAsset("uatom", "1000000000", 1)),
+			newBalancerPool(t, 2, "0.002", balancerAsset("uosmo", "2000000000000", 1), balancerAsset("uatom", "100000000000", 1)),
+		)
+		amountIn, _, err := simulator.OptimalArbitrageAmount(shallow, hops, cosmosSdk.NewInt(1000000000000))
+		maxAmountIn := 10000000000 * simulator.MaxReserveRatio
+		if err != nil {
+			t.Fatal(err)
+		} else if in := float64(amountIn.Int64()); in > maxAmountIn || in < maxAmountIn-2 {
+			t.Errorf("amount in is %s, expected the maximum reserve ratio of pool 1 (%.0f)", amountIn, maxAmountIn)
+		}
+	})
+
+	t.Run("no arbitrage", func(t *testing.T) {
+		balanced := poolStates(t,
+			newBalancerPool(t, 1, "0.002", balancerAsset("uosmo", "2500000000000", 1), balancerAsset("uatom", "250000000000", 1)),
+			newBalancerPool(t, 2, "0.002", balancerAsset("uosmo", "2500000000000", 1), balancerAsset("uatom", "250000000000", 1)),
+		)
+		_, _, err := simulator.OptimalArbitrageAmount(balanced, hops, cosmosSdk.NewInt(1000000000000))
+		if !errors.Is(err, simulator.ErrUnprofitableArbitrage) {
+			t.Errorf("expected ErrUnprofitableArbitrage, got %v", err)
+		}
+	})
+
+	t.Run("not a cycle", func(t *testing.T) {
+		_, _, err := simulator.OptimalArbitrageAmount(pools, hops[:1], cosmosSdk.NewInt(1000000000000))
+		if err == nil {
+			t.Error("expected an error for a route that doesn't end in uosmo")
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	cosmosTypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Recomputes the user swap and the arbitrage swaps (which execute after the user swap) from the pool state.
+// Returns an error if the simulated amounts differ from ours by more than the tolerance (e.g. .01 for 1%).
+// Otherwise the simulated amounts out (and the arbitrage profit estimates) are replaced with our recomputed amounts.
+func VerifySimulation(pools PoolStates, result *SimulatedSwapResult, tolerance float64) error {
+	if result.SimulatedUserSwap == nil {
+		return errors.New("simulation has no user swap")
+	}
+
+	pools = pools.Clone()
+	userSwap := result.SimulatedUserSwap
+	userAmountIn, err := verifySwap(pools, userSwap, tolerance)
+	if err != nil {
+		return fmt.Errorf("user swap: %s", err.Error())
+	}
+
+	//The user's TX (and the arbitrage in the same TX) would fail if the minimum amount out can't be met
+	if !userSwap.IsExactAmountOut() && !userSwap.TokenOutMinAmount.IsNil() && userSwap.TokenOutMinAmount.GT(userSwap.TokenOutAmount) {
+		return fmt.Errorf("user swap: minimum amount out %s exceeds amount out %s", userSwap.TokenOutMinAmount, userSwap.TokenOutAmount)
+	}
+
+	//The arbitrage trades against the pools as they are after the user's swap (and any earlier arbitrage)
+	err = pools.ApplyRoute(userSwap.Hops(), userAmountIn)
+	if err != nil {
+		return fmt.Errorf("user swap: %s", err.Error())
+	}
+
+	for i, arbSwap := range result.GetArbitrageSwaps() {
+		arbAmountIn, err := verifySwap(pools, arbSwap.SimulatedSwap, tolerance)
+		if err != nil {
+			return fmt.Errorf("arbitrage swap %d: %s", i+1, err.Error())
+		}
+
+		err = pools.ApplyRoute(arbSwap.SimulatedSwap.Hops(), arbAmountIn)
+		if err != nil {
+			return fmt.Errorf("arbitrage swap %d: %s", i+1, err.Error())
+		}
+
+		err = arbSwap.updateEstimatedProfit()
+		if err != nil {
+			return fmt.Errorf("arbitrage swap %d: %s", i+1, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Checks the swap's amount out against the pool state and updates it to the recomputed amount.
+// Returns the amount in that was used for the recomputation.
+func verifySwap(pools PoolStates, swap *SimulatedSwap, tolerance float64) (float64, error) {
+	tokenIn := swap.GetTokenIn()
+	amountIn := toFloat(tokenIn.Amount.ToDec())
+	if amountIn <= 0 {
+		return 0, errors.New("amount in must be positive")
+	}
+
+	amountOut, err := pools.SimulateRoute(swap.Hops(), amountIn)
+	if err != nil {
+		return 0, err
+	}
+	amountOut = math.Floor(amountOut)
+
+	if swap.IsExactAmountOut() {
+		//The maximum amount in must buy at least the exact amount out
+		required := toFloat(swap.TokenOut.Amount.ToDec())
+		if amountOut < required*(1-tolerance) {
+			return 0, fmt.Errorf("maximum amount in %s only buys %.0f%s, not %s", tokenIn, amountOut, swap.TokenOut.Denom, swap.TokenOut)
+		}
+		//The pools only move by the amount actually spent, which may be well under the maximum
+		return pools.AmountInForOut(swap.Hops(), required, amountIn), nil
+	}
+
+	claimed := toFloat(swap.GetTokenOut().Amount.ToDec())
+	if math.Abs(claimed-amountOut) > amountOut*tolerance {
+		return 0, fmt.Errorf("simulated amount out %.0f%s, but pools return %.0f%s", claimed, swap.TokenOutDenom, amountOut, swap.TokenOutDenom)
+	}
+
+	swap.TokenOutAmount, err = IntFromFloat(amountOut)
+	if err != nil {
+		return 0, err
+	}
+	return amountIn, nil
+}
+
+// Sets the profit estimates from the swap's amount in and (recomputed) amount out
+func (arbSwap *ArbitrageSwap) updateEstimatedProfit() error {
+	tokenIn := arbSwap.SimulatedSwap.GetTokenIn()
+	tokenOut := arbSwap.SimulatedSwap.GetTokenOut()
+	if tokenIn.Denom != tokenOut.Denom {
+		return fmt.Errorf("arbitrage must start and end with the same denom, not %s and %s", tokenIn.Denom, tokenOut.Denom)
+	}
+
+	profit := cosmosTypes.Coin{Denom: tokenOut.Denom, Amount: tokenOut.Amount.Sub(tokenIn.Amount)}
+	arbSwap.EstimatedProfitHumanReadable = profit.String()
+	arbSwap.EstimatedProfitBaseAmount = profit.Amount.String()
+	return nil
+}
@@ -1,14 +1,8 @@
 package zenith
 
 import (
-	"bytes"
-	b64 "encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
-	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/DefiantLabs/RedpointSwap/config"
@@ -16,271 +10,166 @@ import (
 	"github.com/DefiantLabs/RedpointSwap/simulator"
 	cosmosClient "github.com/cosmos/cosmos-sdk/client"
 	cosmosSdk "github.com/cosmos/cosmos-sdk/types"
-	bankTypes "github.com/cosmos/cosmos-sdk/x/bank/types"
-	gamm "github.com/osmosis-labs/osmosis/v13/x/gamm/types"
 )
 
-// Will either return an error with a reason the simulation shouldn't be submitted to Zenith,
-// or the gas fee, zenith fee, and minimum arb amount to submit the arb to Mekatek Zenith API
-func IsZenithEligible(simResult simulator.SimulatedSwapResult, txClient cosmosClient.Context) (
-	arbSwaps []cosmosSdk.Msg,
-	gasFeeInt cosmosSdk.Int,
-	zenithFeeInt cosmosSdk.Int,
-	totalArbFees cosmosSdk.Int,
-	err error,
-) {
-	if !simResult.HasArbitrageOpportunity || (simResult.ArbitrageSwap.SimulatedSwap.TokenIn.Denom != simResult.ArbitrageSwap.SimulatedSwap.TokenOutDenom) {
-		err = errors.New("bad request (arbitrage params invalid) -- do not submit TX with Zenith")
-		return
-	}
-
-	conf := config.Conf
-
-	arbTokenIn := simResult.ArbitrageSwap.SimulatedSwap.TokenIn
-	maxBid, err := cosmosSdk.ParseCoinNormalized(conf.Zenith.MaximumBidAmount)
-	if err != nil {
-		err = errors.New("server misconfiguration (zenith MaximumBidAmount), please notify administrator")
-		return
-	} else if arbTokenIn.Denom != maxBid.Denom {
-		err = fmt.Errorf("request arb denom is %s, but max bid denom configured as %s", arbTokenIn.Denom, maxBid.Denom)
-		return
-	}
-
-	estimatedAmountOut := simResult.ArbitrageSwap.SimulatedSwap.TokenOutAmount.ToDec()
-	estimatedArbRevenue := estimatedAmountOut.Sub(arbTokenIn.Amount.ToDec())
-	asF := strconv.FormatFloat(conf.Zenith.BidPercentage, 'f', 6, 64)
-	zenithBidPercent, err := cosmosSdk.NewDecFromStr(asF)
-	if err != nil {
-		err = errors.New("server misconfiguration (zenith bid percentage), please notify administrator")
-		return
-	} else if estimatedArbRevenue.LTE(cosmosSdk.ZeroDec()) {
-		err = errors.New("arbitrage not profitable")
-		return
-	}
-
-	zenithFee := estimatedArbRevenue.Mul(zenithBidPercent)
-	if zenithFee.GT(maxBid.Amount.ToDec()) {
-		zenithFee = maxBid.Amount.ToDec()
-	}
-
-	if zenithFee.LTE(cosmosSdk.ZeroDec()) {
-		err = errors.New("zenith fee calculation error")
-		return
-	}
-
-	var gasFee uint64
-	gasFee, err = osmosis.EstimateArbGas(simResult.ArbitrageSwap.SimulatedSwap.TokenIn, simResult.ArbitrageSwap.SimulatedSwap.Routes)
-	if err != nil {
-		return
-	}
+// Estimated fees and revenue for one of the simulation's arbitrage swaps. All amounts are in the fee denom (uosmo).
+type ArbFeeEstimate struct {
+	Swap       *simulator.SimulatedSwap
+	Gas        cosmosSdk.Int //Gas the arbitrage swap's message uses
+	ZenithFee  cosmosSdk.Int //This swap's share of the Zenith bid
+	TotalFees  cosmosSdk.Int //Gas fee plus Zenith fee
+	Revenue    cosmosSdk.Int //Estimated arbitrage revenue
+	Profitable bool          //Only profitable arbitrage swaps are submitted to Zenith
+}
 
-	gasFeeInt = cosmosSdk.NewIntFromUint64(gasFee)
-	gasFeeUosmo := gasFeeInt.Quo(cosmosSdk.NewInt(200)) //equivalent of dividing by .005, which is the gasPrice amount
-	if gasFeeInt.Equal(cosmosSdk.ZeroInt()) {
-		err = errors.New("arbitrage swap must have 2-5 routes")
-		return
+// Will either return an error with a reason the simulation shouldn't be submitted to Zenith,
+// or the estimated fees (in the fee denom) the hot wallet will pay for each arbitrage swap it submits to Mekatek Zenith API.
+// The arbitrage can start and end in any denom the hot wallet holds, but gas and Zenith fees are paid in the fee denom.
+// All returned amounts are in the fee denom (uosmo), including the estimated arbitrage revenue.
+// The bid strategy prices the Zenith bid for the profitable arbitrage swaps, which split it in proportion to their revenue.
+func EstimateArbFees(simResult simulator.SimulatedSwapResult, queryClient cosmosClient.Context, timeLeft time.Duration, kind string) ([]ArbFeeEstimate, error) {
+	simulatedArbSwaps := simResult.GetArbitrageSwaps()
+	if len(simulatedArbSwaps) == 0 {
+		return nil, errors.New("bad request (arbitrage params invalid) -- do not submit TX with Zenith")
+	}
+
+	estimates := []ArbFeeEstimate{}
+	for _, arbSwap := range simulatedArbSwaps {
+		estimate, err := estimateSwapFees(arbSwap.SimulatedSwap, queryClient)
+		if err != nil {
+			return nil, err
+		}
+		estimates = append(estimates, estimate)
 	}
 
-	arbSwaps, err = osmosis.BuildArbitrageSwap(txClient, simResult.ArbitrageSwap.SimulatedSwap.TokenIn, simResult.ArbitrageSwap.SimulatedSwap.Routes)
+	err := splitBid(estimates, timeLeft, kind)
 	if err != nil {
-		err = errors.New("issue building arbitrage swap")
-		return
-	}
-
-	zenithFeeInt = zenithFee.TruncateInt()
-	totalArbFees = gasFeeUosmo.Add(zenithFeeInt)
-
-	//Make sure the arbitrage TX will profit when we consider the zenith fee and TX fee
-	arbMinOutDec := arbTokenIn.Amount.ToDec().Add(totalArbFees.ToDec())
-	if arbMinOutDec.GT(estimatedAmountOut) {
-		err = errors.New("not zenith eligible (unprofitable)")
+		return nil, err
 	}
-
-	return
+	return estimates, nil
 }
 
-// Will either return an error with a reason the simulation shouldn't be submitted to Zenith,
-// or the gas fee, zenith fee, and minimum arb amount to submit the arb to Mekatek Zenith API
-func EstimateArbFees(simResult simulator.SimulatedSwapResult) (
-	totalArbFees cosmosSdk.Int,
-	err error,
-) {
-	if !simResult.HasArbitrageOpportunity || (simResult.ArbitrageSwap.SimulatedSwap.TokenIn.Denom != simResult.ArbitrageSwap.SimulatedSwap.TokenOutDenom) {
-		err = errors.New("bad request (arbitrage params invalid) -- do not submit TX with Zenith")
-		return
-	}
-
+// Prices the bid for the arbitrage swaps with the bid strategy and splits it between them in proportion to their revenue.
+// Swaps that don't profit after their share of the bid are marked unprofitable and left out, which changes the bid for the others.
+// Block bids compete for the whole block, so they bid a multiple of the strategy's bid (see config BlockBidMultiplier).
+func splitBid(estimates []ArbFeeEstimate, timeLeft time.Duration, kind string) error {
 	conf := config.Conf
-
-	arbTokenIn := simResult.ArbitrageSwap.SimulatedSwap.TokenIn
-	maxBid, err := cosmosSdk.ParseCoinNormalized(conf.Zenith.MaximumBidAmount)
+	maxBid, err := cosmosSdk.ParseCoinNormalized(conf.GetZenithMaximumBidAmount(kind))
 	if err != nil {
-		err = errors.New("server misconfiguration (zenith MaximumBidAmount), please notify administrator")
-		return
-	} else if arbTokenIn.Denom != maxBid.Denom {
-		err = fmt.Errorf("request arb denom is %s, but max bid denom configured as %s", arbTokenIn.Denom, maxBid.Denom)
-		return
+		return errors.New("server misconfiguration (zenith maximum bid amount), please notify administrator")
+	} else if maxBid.Denom != osmosis.FeeDenom {
+		return fmt.Errorf("max bid denom configured as %s, but zenith fees are paid in %s", maxBid.Denom, osmosis.FeeDenom)
 	}
 
-	estimatedAmountOut := simResult.ArbitrageSwap.SimulatedSwap.TokenOutAmount.ToDec()
-	estimatedArbRevenue := estimatedAmountOut.Sub(arbTokenIn.Amount.ToDec())
-	asF := strconv.FormatFloat(conf.Zenith.BidPercentage, 'f', 6, 64)
-	zenithBidPercent, err := cosmosSdk.NewDecFromStr(asF)
+	strategy, err := GetBidStrategy()
 	if err != nil {
-		err = errors.New("server misconfiguration (zenith bid percentage), please notify administrator")
-		return
-	} else if estimatedArbRevenue.LTE(cosmosSdk.ZeroDec()) {
-		err = errors.New("arbitrage not profitable")
-		return
-	}
-
-	zenithFee := estimatedArbRevenue.Mul(zenithBidPercent)
-	if zenithFee.GT(maxBid.Amount.ToDec()) {
-		zenithFee = maxBid.Amount.ToDec()
-	}
-
-	if zenithFee.LTE(cosmosSdk.ZeroDec()) {
-		err = errors.New("zenith fee calculation error")
-		return
+		return err
 	}
 
-	var gasFee uint64
-	gasFee, err = osmosis.EstimateArbGas(simResult.ArbitrageSwap.SimulatedSwap.TokenIn, simResult.ArbitrageSwap.SimulatedSwap.Routes)
-	if err != nil {
-		return
+	multiplier := cosmosSdk.OneDec()
+	if kind == BidKindBlock {
+		multiplier, err = toDec(conf.GetZenithBlockBidMultiplier())
+		if err != nil {
+			return errors.New("server misconfiguration (zenith BlockBidMultiplier), please notify administrator")
+		}
 	}
 
-	gasFeeInt := cosmosSdk.NewIntFromUint64(gasFee)
-	gasFeeUosmo := gasFeeInt.Quo(cosmosSdk.NewInt(200)) //equivalent of dividing by .005, which is the gasPrice amount
-	if gasFeeInt.Equal(cosmosSdk.ZeroInt()) {
-		err = errors.New("arbitrage swap must have 2-5 routes")
-		return
+	inBid := make([]bool, len(estimates))
+	for i := range inBid {
+		inBid[i] = true
 	}
 
-	zenithFeeInt := zenithFee.TruncateInt()
-	totalArbFees = gasFeeUosmo.Add(zenithFeeInt)
-
-	return
-}
-
-func GetZenithBid(zBlock *FutureBlock, req UserZenithRequest, txClient cosmosClient.Context) ([]string, [][]byte, cosmosSdk.Int, error) {
-	txs := [][]byte{}
-
-	// The hot wallet will protect itself by only submitting bids in a way that guarantees profits (e.g. arb profits > bid amount)
-	// This also considers many other factors such as gas fees
-	arbSwaps, gasFeeInt, zenithFeeInt, totalArbFees, err := IsZenithEligible(req.SimulatedSwap, txClient)
-	if err != nil {
-		return nil, nil, totalArbFees, err
-	}
+	for dropped := true; dropped; {
+		dropped = false
+		totalRevenue := cosmosSdk.ZeroInt()
+		for i, estimate := range estimates {
+			if inBid[i] {
+				totalRevenue = totalRevenue.Add(estimate.Revenue)
+			}
+		}
+		if totalRevenue.IsZero() {
+			break
+		}
 
-	userTxBytes, err := b64.StdEncoding.DecodeString(req.SwapTx)
-	if err != nil {
-		return nil, nil, totalArbFees, errors.New("provided user tx must be base 64 encoded")
-	}
-	txs = append(txs, userTxBytes)
+		//The bid for the block can't exceed the configured maximum
+		bid := strategy.Bid(totalRevenue, timeLeft).ToDec().Mul(multiplier).TruncateInt()
+		if bid.GT(maxBid.Amount) {
+			bid = maxBid.Amount
+		}
+		if !bid.IsPositive() {
+			return errors.New("zenith fee calculation error")
+		}
 
-	decoder := txClient.TxConfig.TxDecoder()
-	osmosisTx, err := decoder(userTxBytes)
-	if err != nil {
-		return nil, nil, totalArbFees, errors.New("TX must be a valid Osmosis TX")
-	}
+		for i := range estimates {
+			if !inBid[i] {
+				continue
+			}
 
-	//Whether or not the Bid was submitted with a signed user TX that matches the arbitrage simulator (e.g. the simulator simulated this TX).
-	//This isn't intended to be a security check, it is just a sanity check so we don't accidentally place stupid bids.
-	userTxMatchesSimulation := false
-	msgs := osmosisTx.GetMsgs()
-	for _, msg := range msgs {
-		swapIn, ok := msg.(*gamm.MsgSwapExactAmountIn)
-		if ok {
-			if req.SimulatedSwap.SimulatedUserSwap.TokenIn.Denom == swapIn.TokenIn.Denom {
-				diff := swapIn.TokenIn.Amount.Sub(req.SimulatedSwap.SimulatedUserSwap.TokenIn.Amount)
-				absDiff := diff.Abs().ToDec()
-				percentageDiff := absDiff.Quo(req.SimulatedSwap.SimulatedUserSwap.TokenIn.Amount.ToDec())
-				percentDiffFloat, err := percentageDiff.Float64()
-				if err == nil {
-					if percentDiffFloat <= 0.005 { //tolerate .5% difference in case of conversion errors on client
-						userTxMatchesSimulation = true
-					}
-				}
+			estimate := &estimates[i]
+			estimate.ZenithFee = bid.Mul(estimate.Revenue).Quo(totalRevenue)
+			estimate.TotalFees = estimate.Gas.Quo(cosmosSdk.NewInt(200)).Add(estimate.ZenithFee) //dividing by 200 is equivalent to multiplying by the .005 gas price
+			estimate.Profitable = estimate.ZenithFee.IsPositive() && estimate.Revenue.GT(estimate.TotalFees)
+			if !estimate.Profitable {
+				inBid[i] = false
+				dropped = true
 			}
 		}
 	}
 
-	if !userTxMatchesSimulation {
-		return nil, nil, totalArbFees, errors.New("TX ineligible for arbitrage (will not be submitted to Zenith)")
-	}
-
-	bidTxs := []string{req.SwapTx}
-	zenithFeeUosmo, err := zenithFeeInt.ToDec().Float64()
-	if err != nil {
-		return nil, nil, totalArbFees, errors.New("unexpected zenith fee value")
-	}
-
-	hotWalletTxMsgs := arbSwaps
-
-	total := 0.0
-	for _, payment := range zBlock.Auction.Payments {
-		if payment.Denom != "uosmo" {
-			return nil, nil, totalArbFees, fmt.Errorf("app only supports uosmo payments, but zenith auction requires %s", payment.Denom)
-		}
+	return nil
+}
 
-		total += payment.Allocation
-		fee := zenithFeeUosmo * payment.Allocation
-		feeCoin := cosmosSdk.NewCoin("uosmo", cosmosSdk.NewInt(int64(math.Trunc(fee))))
-		msgZenithPayment := &bankTypes.MsgSend{FromAddress: config.HotWalletAddress, ToAddress: payment.Address, Amount: []cosmosSdk.Coin{feeCoin}}
-		hotWalletTxMsgs = append(hotWalletTxMsgs, msgZenithPayment)
+// Gas and revenue (in the fee denom) of the arbitrage swap. The Zenith fee depends on the other swaps in the bid.
+func estimateSwapFees(arbSwap *simulator.SimulatedSwap, queryClient cosmosClient.Context) (ArbFeeEstimate, error) {
+	estimate := ArbFeeEstimate{Swap: arbSwap}
+	arbTokenIn := arbSwap.GetTokenIn()
+	if arbTokenIn.Denom != arbSwap.GetTokenOut().Denom {
+		return estimate, errors.New("bad request (arbitrage params invalid) -- do not submit TX with Zenith")
+	} else if _, ok := config.Conf.GetArbitrageCapitalForDenom(arbTokenIn.Denom); !ok {
+		return estimate, fmt.Errorf("request arb denom is %s, but the hot wallet does not hold that denom for arbitrage", arbTokenIn.Denom)
 	}
 
-	if total != 1.0 {
-		return nil, nil, totalArbFees, errors.New("zenith auction payments don't equal 1.0")
+	estimatedAmountOut := arbSwap.GetTokenOut().Amount.ToDec()
+	estimatedArbRevenue := estimatedAmountOut.Sub(arbTokenIn.Amount.ToDec())
+	if estimatedArbRevenue.LTE(cosmosSdk.ZeroDec()) {
+		return estimate, errors.New("arbitrage not profitable")
 	}
 
-	zenithTxBytes, err := osmosis.GetSignedTx(txClient, hotWalletTxMsgs, gasFeeInt.Uint64())
+	//Fees are paid in the fee denom, so compare them against the value of the revenue in the fee denom
+	revenueFeeDenom, err := osmosis.ToFeeDenom(queryClient, estimatedArbRevenue.TruncateInt(), arbTokenIn.Denom)
 	if err != nil {
-		return nil, nil, totalArbFees, errors.New("problem signing zenith arbitrage & payments TXs")
+		return estimate, fmt.Errorf("could not price arbitrage revenue in %s: %s", osmosis.FeeDenom, err.Error())
+	} else if !revenueFeeDenom.IsPositive() {
+		return estimate, errors.New("arbitrage not profitable")
 	}
 
-	txs = append(txs, zenithTxBytes)
-	zenithTxB64 := b64.StdEncoding.EncodeToString(zenithTxBytes)
-	bidTxs = append(bidTxs, zenithTxB64)
-	return bidTxs, txs, totalArbFees, nil
-}
-
-func PlaceBid(bidReq *ZenithBidRequest) error {
-	reqBytes, err := json.Marshal(bidReq)
+	gasFee, err := osmosis.EstimateArbGas(arbSwap)
 	if err != nil {
-		err := fmt.Sprintf("failed to marshal request %+v to json for zenith api", bidReq)
-		fmt.Println(err)
-		return errors.New(err)
+		return estimate, err
 	}
 
-	//Send the request to the Zenith API
-	httpReq, err := http.NewRequest("POST", config.Conf.Zenith.ZenithBidUrl, bytes.NewBuffer(reqBytes))
-	if err != nil {
-		fmt.Println(err.Error())
-		return err
+	gasFeeInt := cosmosSdk.NewIntFromUint64(gasFee)
+	if gasFeeInt.Equal(cosmosSdk.ZeroInt()) {
+		return estimate, errors.New("arbitrage swap must have 2-5 routes")
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{
-		Timeout: 3 * time.Second,
-	}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		fmt.Println(err.Error())
-		return err
-	}
-	defer resp.Body.Close()
+	estimate.Gas = gasFeeInt
+	estimate.ZenithFee = cosmosSdk.ZeroInt()
+	estimate.TotalFees = gasFeeInt.Quo(cosmosSdk.NewInt(200))
+	estimate.Revenue = revenueFeeDenom
+	estimate.Profitable = true //Until the bid is split between the swaps
+	return estimate, nil
+}
 
-	var bidResponse BidResponse
-	err = json.NewDecoder(resp.Body).Decode(&bidResponse)
-	if err != nil {
-		fmt.Println("failed to decode response from zenith api")
-		return err
-	} else if resp.StatusCode != 200 {
-		fmt.Printf("Zenith responded with bad status code %d\n", resp.StatusCode)
-		return errors.New("Zenith endpoint bad status code")
+// Tolerate .5% difference between the signed TX and the simulation in case of conversion errors on client
+func matchesSimulatedAmount(actual cosmosSdk.Coin, simulated cosmosSdk.Coin) bool {
+	if actual.Denom != simulated.Denom || simulated.Amount.IsNil() || !simulated.Amount.IsPositive() {
+		return false
 	}
 
-	return nil
+	diff := actual.Amount.Sub(simulated.Amount)
+	absDiff := diff.Abs().ToDec()
+	percentageDiff := absDiff.Quo(simulated.Amount.ToDec())
+	percentDiffFloat, err := percentageDiff.Float64()
+	return err == nil && percentDiffFloat <= 0.005
 }
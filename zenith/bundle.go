@@ -0,0 +1,309 @@
+package zenith
+
+import (
+	b64 "encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/DefiantLabs/RedpointSwap/osmosis"
+	"github.com/DefiantLabs/RedpointSwap/simulator"
+	cosmosClient "github.com/cosmos/cosmos-sdk/client"
+	cosmosSdk "github.com/cosmos/cosmos-sdk/types"
+	bankTypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	gamm "github.com/osmosis-labs/osmosis/v13/x/gamm/types"
+	"go.uber.org/zap"
+)
+
+// A queued user request that can be bundled into a bid
+type BundleRequest struct {
+	Id      string //The caller's ID for the request, see BundledTrade
+	Request *UserZenithRequest
+}
+
+// A user request in a bundle, and the hot wallet TX that captures its arbitrage
+type BundledTrade struct {
+	Id           string
+	Request      *UserZenithRequest
+	Simulation   simulator.SimulatedSwapResult //The request's simulation, with the arbitrage sized for its place in the bundle
+	Estimates    []ArbFeeEstimate              //Fees and revenue of the arbitrage swaps in the bid
+	UserTx       []byte
+	HotWalletTx  []byte        //The arbitrage swaps and this trade's share of the auction payments
+	ZenithFee    cosmosSdk.Int //This trade's share of the bid
+	TotalArbFees cosmosSdk.Int //Gas fee plus Zenith fee
+
+	arbMsgs []cosmosSdk.Msg
+}
+
+// User requests bundled into one bid for a Zenith block. Each trade's user TX is followed by its hot wallet TX,
+// and each trade's arbitrage is sized for the pools as the earlier trades in the bundle leave them.
+type Bundle struct {
+	Kind      string          //The kind of bid the bundle is placed in, top or block
+	Trades    []*BundledTrade //In execution order
+	BidTxs    []string        //Base 64 encoded TXs, in execution order
+	Txs       [][]byte
+	BidAmount cosmosSdk.Int //Total paid to the auction's payment addresses (in the fee denom)
+}
+
+// Estimated arbitrage revenue of the bundle after gas and the bid (in the fee denom)
+func (bundle *Bundle) ExpectedProfit() cosmosSdk.Int {
+	profit := cosmosSdk.ZeroInt()
+	for _, trade := range bundle.Trades {
+		for _, estimate := range trade.Estimates {
+			profit = profit.Add(estimate.Revenue).Sub(estimate.TotalFees)
+		}
+	}
+	return profit
+}
+
+// A request whose user TX checked out, ready to be placed in the bundle
+type bundleCandidate struct {
+	BundleRequest
+	userTx  []byte
+	revenue cosmosSdk.Int //Estimated arbitrage revenue on its own, used to order the bundle
+}
+
+// Bundles as many of the requests as will profit into one bid for the Zenith block (up to config MaxBundleRequests).
+// Requests with the most arbitrage go first. A request is left out if its user TX would fail after the earlier trades,
+// or none of its arbitrage profits after them (it can be bid on in a later block).
+// The bid strategy prices one bid for the whole bundle, which the trades split in proportion to their arbitrage revenue.
+// Only requests of the given kind are bundled, top and block bids are separate auctions.
+func BuildBundle(zBlock *FutureBlock, kind string, reqs []BundleRequest, txClient cosmosClient.Context) (*Bundle, error) {
+	total := 0.0
+	for _, payment := range zBlock.Auction.Payments {
+		if payment.Denom != osmosis.FeeDenom {
+			return nil, fmt.Errorf("app only supports %s payments, but zenith auction requires %s", osmosis.FeeDenom, payment.Denom)
+		}
+		total += payment.Allocation
+	}
+	if total != 1.0 {
+		return nil, errors.New("zenith auction payments don't equal 1.0")
+	}
+
+	candidates := []bundleCandidate{}
+	for _, req := range reqs {
+		if req.Request.BidKind() != kind {
+			continue
+		}
+
+		userTx, err := checkUserTx(req.Request, txClient)
+		if err != nil {
+			config.Logger.Info("Zenith request not bundled", zap.String("id", req.Id), zap.Error(err))
+			continue
+		}
+
+		//The bid is priced for the whole bundle, so only the revenue matters here
+		revenue := cosmosSdk.ZeroInt()
+		for _, arbSwap := range req.Request.SimulatedSwap.GetArbitrageSwaps() {
+			estimate, err := estimateSwapFees(arbSwap.SimulatedSwap, txClient)
+			if err == nil {
+				revenue = revenue.Add(estimate.Revenue)
+			}
+		}
+		candidates = append(candidates, bundleCandidate{BundleRequest: req, userTx: userTx, revenue: revenue})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].revenue.GT(candidates[j].revenue)
+	})
+
+	//Arbitrage that doesn't profit after its share of the bid is left out, which changes the pools for the later trades
+	excluded := map[string]bool{}
+	for {
+		trades, err := sequenceTrades(candidates, excluded, txClient)
+		if err != nil {
+			return nil, err
+		} else if len(trades) == 0 {
+			return nil, errors.New("not zenith eligible (unprofitable)")
+		}
+
+		estimates := []ArbFeeEstimate{}
+		timeLeft := time.Duration(0)
+		for _, trade := range trades {
+			estimates = append(estimates, trade.Estimates...)
+			//The bid is as urgent as the request that expires first
+			if tradeTimeLeft := trade.Request.TimeLeft(zBlock.ProjectedBlocktime); tradeTimeLeft > 0 && (timeLeft == 0 || tradeTimeLeft < timeLeft) {
+				timeLeft = tradeTimeLeft
+			}
+		}
+
+		err = splitBid(estimates, timeLeft, kind)
+		if err != nil {
+			return nil, err
+		}
+
+		unprofitable := false
+		i := 0
+		for _, trade := range trades {
+			trade.Estimates = estimates[i : i+len(trade.Estimates)]
+			i += len(trade.Estimates)
+			for _, estimate := range trade.Estimates {
+				if !estimate.Profitable {
+					excluded[arbSwapKey(trade.Id, estimate.Swap)] = true
+					unprofitable = true
+				}
+			}
+		}
+
+		if !unprofitable {
+			return signBundle(zBlock, kind, trades, txClient)
+		}
+	}
+}
+
+// Places the candidates one after another on the current pools, sizing each trade's arbitrage for the pools the earlier trades leave.
+// The excluded arbitrage swaps are left out. Trades with no arbitrage left are skipped.
+func sequenceTrades(candidates []bundleCandidate, excluded map[string]bool, txClient cosmosClient.Context) ([]*BundledTrade, error) {
+	poolIds := []uint64{}
+	for _, candidate := range candidates {
+		poolIds = append(poolIds, candidate.Request.SimulatedSwap.PoolIds()...)
+	}
+
+	pools, err := osmosis.GetPoolStates(txClient, poolIds)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", osmosis.ErrPoolDataUnavailable, err.Error())
+	}
+
+	hotWalletAddress := txClient.GetFromAddress().String()
+	maxTrades := config.Conf.GetZenithMaxBundleRequests()
+	trades := []*BundledTrade{}
+	for _, candidate := range candidates {
+		if len(trades) == maxTrades {
+			break
+		}
+
+		//The simulation is re-sized for the bundle, the queued request keeps its own
+		simulation := candidate.Request.SimulatedSwap
+		arbSwaps := []*simulator.ArbitrageSwap{}
+		for _, arbSwap := range simulation.GetArbitrageSwaps() {
+			if !excluded[arbSwapKey(candidate.Id, arbSwap.SimulatedSwap)] {
+				arbSwapCopy := *arbSwap
+				arbSwaps = append(arbSwaps, &arbSwapCopy)
+			}
+		}
+		simulation.SetArbitrageSwaps(arbSwaps)
+
+		trial := pools.Clone()
+		err := osmosis.ApplyUserSwap(trial, simulation.SimulatedUserSwap)
+		if err == nil {
+			err = osmosis.ResizeArbitrage(trial.Clone(), &simulation, hotWalletAddress)
+		}
+		if err != nil {
+			config.Logger.Info("Zenith request left out of bundle", zap.String("id", candidate.Id), zap.Int("position", len(trades)), zap.Error(err))
+			continue
+		}
+
+		trade := &BundledTrade{Id: candidate.Id, Request: candidate.Request, Simulation: simulation, UserTx: candidate.userTx}
+		swaps := []*simulator.SimulatedSwap{}
+		for _, arbSwap := range simulation.GetArbitrageSwaps() {
+			estimate, estimateErr := estimateSwapFees(arbSwap.SimulatedSwap, txClient)
+			if estimateErr != nil {
+				err = estimateErr
+				break
+			}
+			trade.Estimates = append(trade.Estimates, estimate)
+			swaps = append(swaps, arbSwap.SimulatedSwap)
+		}
+
+		//Sizes the arbitrage the same way as above, and moves the pools past it for the next trade
+		if err == nil {
+			trade.arbMsgs, err = osmosis.BuildArbitragesOnPools(txClient, trial, swaps)
+		}
+		if err != nil {
+			config.Logger.Info("Zenith request left out of bundle", zap.String("id", candidate.Id), zap.Int("position", len(trades)), zap.Error(err))
+			continue
+		}
+
+		pools = trial
+		trades = append(trades, trade)
+	}
+
+	return trades, nil
+}
+
+// Identifies an arbitrage swap of a request across bundle attempts (the amounts change as it is re-sized)
+func arbSwapKey(requestId string, arbSwap *simulator.SimulatedSwap) string {
+	return fmt.Sprintf("%s/%s/%v", requestId, arbSwap.GetTokenIn().Denom, arbSwap.PoolIds())
+}
+
+// Signs each trade's hot wallet TX (its arbitrage and its share of the auction payments) in bundle order.
+// The hot wallet's sequence goes up by one for each TX, and no other hot wallet TX is broadcast until the auction block.
+func signBundle(zBlock *FutureBlock, kind string, trades []*BundledTrade, txClient cosmosClient.Context) (*Bundle, error) {
+	bundle := &Bundle{Kind: kind, BidAmount: cosmosSdk.ZeroInt()}
+	for i, trade := range trades {
+		gasFeeInt, zenithFeeInt, totalArbFees := cosmosSdk.ZeroInt(), cosmosSdk.ZeroInt(), cosmosSdk.ZeroInt()
+		for _, estimate := range trade.Estimates {
+			gasFeeInt = gasFeeInt.Add(estimate.Gas)
+			zenithFeeInt = zenithFeeInt.Add(estimate.ZenithFee)
+			totalArbFees = totalArbFees.Add(estimate.TotalFees)
+		}
+
+		zenithFeeUosmo, err := zenithFeeInt.ToDec().Float64()
+		if err != nil {
+			return nil, errors.New("unexpected zenith fee value")
+		}
+
+		//Each trade pays its share of the bid, so the payments show up in its own TXs
+		hotWalletTxMsgs := append([]cosmosSdk.Msg{}, trade.arbMsgs...)
+		for _, payment := range zBlock.Auction.Payments {
+			fee := zenithFeeUosmo * payment.Allocation
+			feeCoin := cosmosSdk.NewCoin(osmosis.FeeDenom, cosmosSdk.NewInt(int64(math.Trunc(fee))))
+			msgZenithPayment := &bankTypes.MsgSend{FromAddress: config.HotWalletAddress, ToAddress: payment.Address, Amount: []cosmosSdk.Coin{feeCoin}}
+			hotWalletTxMsgs = append(hotWalletTxMsgs, msgZenithPayment)
+			bundle.BidAmount = bundle.BidAmount.Add(feeCoin.Amount)
+		}
+
+		zenithTxBytes, err := osmosis.GetSignedTxAtSequence(txClient, hotWalletTxMsgs, gasFeeInt.Uint64(), uint64(i), zBlock.Height)
+		if err != nil {
+			return nil, errors.New("problem signing zenith arbitrage & payments TXs")
+		}
+
+		trade.HotWalletTx = zenithTxBytes
+		trade.ZenithFee = zenithFeeInt
+		trade.TotalArbFees = totalArbFees
+		bundle.Trades = append(bundle.Trades, trade)
+		bundle.Txs = append(bundle.Txs, trade.UserTx, zenithTxBytes)
+		bundle.BidTxs = append(bundle.BidTxs, trade.Request.SwapTx, b64.StdEncoding.EncodeToString(zenithTxBytes))
+	}
+
+	return bundle, nil
+}
+
+// Decodes the request's user TX and makes sure it matches the simulation
+func checkUserTx(req *UserZenithRequest, txClient cosmosClient.Context) ([]byte, error) {
+	userTxBytes, err := b64.StdEncoding.DecodeString(req.SwapTx)
+	if err != nil {
+		return nil, errors.New("provided user tx must be base 64 encoded")
+	}
+
+	decoder := txClient.TxConfig.TxDecoder()
+	osmosisTx, err := decoder(userTxBytes)
+	if err != nil {
+		return nil, errors.New("TX must be a valid Osmosis TX")
+	}
+
+	//Whether or not the Bid was submitted with a signed user TX that matches the arbitrage simulator (e.g. the simulator simulated this TX).
+	//This isn't intended to be a security check, it is just a sanity check so we don't accidentally place stupid bids.
+	userSwap := req.SimulatedSwap.SimulatedUserSwap
+	if userSwap == nil {
+		return nil, errors.New("simulation has no user swap")
+	}
+
+	for _, msg := range osmosisTx.GetMsgs() {
+		switch swap := msg.(type) {
+		case *gamm.MsgSwapExactAmountIn:
+			if !userSwap.IsExactAmountOut() && matchesSimulatedAmount(swap.TokenIn, userSwap.TokenIn) {
+				return userTxBytes, nil
+			}
+		case *gamm.MsgSwapExactAmountOut:
+			if userSwap.IsExactAmountOut() && matchesSimulatedAmount(swap.TokenOut, userSwap.TokenOut) {
+				return userTxBytes, nil
+			}
+		}
+	}
+
+	return nil, errors.New("TX ineligible for arbitrage (will not be submitted to Zenith)")
+}
@@ -0,0 +1,217 @@
+package zenith
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/avast/retry-go"
+	"go.uber.org/zap"
+)
+
+// Outcome of a bid, see BidStatusResponse
+const (
+	BidStatusPending  = "pending"  //The auction height hasn't been reached yet
+	BidStatusWon      = "won"      //Our bid won the auction, the TXs are in the block
+	BidStatusLost     = "lost"     //Another bid won the auction
+	BidStatusRejected = "rejected" //Zenith rejected the bid (e.g. invalid TXs or payments)
+)
+
+// GET request for the status of a bid we placed (the ID is from the BidResponse)
+type BidStatusRequest struct {
+	ChainID string `json:"chain_id"`
+	Height  int64  `json:"height"`
+	Id      string `json:"id"`
+}
+
+type BidStatusResponse struct {
+	ChainID string `json:"chain_id"`
+	Height  int64  `json:"height"`
+	Id      string `json:"id"`
+	Status  string `json:"status"` //One of the BidStatus constants
+}
+
+// Zenith's HTTP API. Every call returns early if the context is done.
+type ZenithClient interface {
+	// Whether or not the height is a Zenith auction, and the payments the winning bid must make if it is
+	GetAuction(ctx context.Context, req *AuctionRequest) (*AuctionResponse, ZenithResponse, error)
+	PlaceBid(ctx context.Context, bidReq *ZenithBidRequest) (*BidResponse, error)
+	// Zenith's view of the bid. Bid outcomes are recorded from the chain (whether the bid's TXs landed at the auction height),
+	// so the status is only informational.
+	GetBidStatus(ctx context.Context, req *BidStatusRequest) (*BidStatusResponse, error)
+}
+
+// Zenith returned an HTTP status we don't handle
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("zenith responded with HTTP status %d: %s", e.StatusCode, e.Body)
+}
+
+// ZenithClient for Mekatek's API (see https://meka.tech/zenith). Bids are queried with a GET on the bid URL.
+type HttpClient struct {
+	AuctionUrl string
+	BidUrl     string
+	Client     *http.Client
+	Retries    uint          //Connection errors and 5xx responses are retried this many times
+	RetryDelay time.Duration //Doubled after each retry
+}
+
+var zenithClient ZenithClient
+var zenithClientLock sync.Mutex
+
+// The client for the configured Zenith endpoints, unless another client was set with SetClient
+func GetClient() ZenithClient {
+	zenithClientLock.Lock()
+	defer zenithClientLock.Unlock()
+
+	if zenithClient == nil {
+		conf := config.Conf
+		zenithClient = NewHttpClient(conf.Zenith.ZenithAuctionUrl, conf.Zenith.ZenithBidUrl, conf.GetZenithRequestTimeout(), conf.GetZenithRequestRetries())
+	}
+	return zenithClient
+}
+
+// Replace the Zenith client, e.g. with one for a zenithtest.Server
+func SetClient(client ZenithClient) {
+	zenithClientLock.Lock()
+	defer zenithClientLock.Unlock()
+	zenithClient = client
+}
+
+func NewHttpClient(auctionUrl string, bidUrl string, timeout time.Duration, retries uint) *HttpClient {
+	return &HttpClient{
+		AuctionUrl: auctionUrl,
+		BidUrl:     bidUrl,
+		Client:     &http.Client{Timeout: timeout},
+		Retries:    retries,
+		RetryDelay: 100 * time.Millisecond,
+	}
+}
+
+func (c *HttpClient) GetAuction(ctx context.Context, req *AuctionRequest) (*AuctionResponse, ZenithResponse, error) {
+	params := url.Values{}
+	params.Add("chain_id", req.ChainID)
+	params.Add("height", fmt.Sprintf("%d", req.Height))
+
+	var auctionResp AuctionResponse
+	statusCode, err := c.do(ctx, http.MethodGet, c.AuctionUrl, params, nil, &auctionResp)
+
+	//Zenith uses the status code to say whether or not the height is an auction
+	switch statusCode {
+	case http.StatusOK:
+		if err != nil {
+			return nil, QueryError, err
+		}
+		return &auctionResp, ZenithAuction, nil
+	case http.StatusGone:
+		return nil, PastAuction, nil
+	case http.StatusTooEarly:
+		return nil, AuctionTooFarInFuture, nil
+	case http.StatusExpectationFailed:
+		return nil, NotZenithAuction, nil
+	}
+
+	if err == nil {
+		err = &StatusError{StatusCode: statusCode}
+	}
+	config.Logger.Error("Zenith auction query", zap.Int64("height", req.Height), zap.Int("HTTP status", statusCode), zap.Error(err))
+	return nil, QueryError, err
+}
+
+// Bids for the same height replace each other, so a bid can safely be retried
+func (c *HttpClient) PlaceBid(ctx context.Context, bidReq *ZenithBidRequest) (*BidResponse, error) {
+	reqBytes, err := json.Marshal(bidReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bid request for zenith api: %w", err)
+	}
+
+	var bidResponse BidResponse
+	_, err = c.do(ctx, http.MethodPost, c.BidUrl, nil, reqBytes, &bidResponse)
+	if err != nil {
+		return nil, err
+	}
+	return &bidResponse, nil
+}
+
+func (c *HttpClient) GetBidStatus(ctx context.Context, req *BidStatusRequest) (*BidStatusResponse, error) {
+	params := url.Values{}
+	params.Add("chain_id", req.ChainID)
+	params.Add("height", fmt.Sprintf("%d", req.Height))
+	params.Add("id", req.Id)
+
+	var statusResp BidStatusResponse
+	_, err := c.do(ctx, http.MethodGet, c.BidUrl, params, nil, &statusResp)
+	if err != nil {
+		return nil, err
+	}
+	return &statusResp, nil
+}
+
+// Sends the request (retrying connection errors and 5xx responses) and decodes a 200 response into result.
+// Returns the last HTTP status code, or 0 if there was no response. Any status other than 200 is a *StatusError.
+func (c *HttpClient) do(ctx context.Context, method string, reqUrl string, params url.Values, body []byte, result any) (int, error) {
+	zenithReq, err := url.Parse(reqUrl)
+	if err != nil {
+		return 0, err
+	}
+	if params != nil {
+		zenithReq.RawQuery = params.Encode()
+	}
+
+	statusCode := 0
+	err = retry.Do(func() error {
+		httpReq, err := http.NewRequestWithContext(ctx, method, zenithReq.String(), bytes.NewReader(body))
+		if err != nil {
+			return retry.Unrecoverable(err)
+		}
+		if body != nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.Client.Do(httpReq)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		statusCode = resp.StatusCode
+		if resp.StatusCode != http.StatusOK {
+			respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			statusErr := &StatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+			if resp.StatusCode >= 500 {
+				return statusErr
+			}
+			return retry.Unrecoverable(statusErr)
+		}
+
+		err = json.NewDecoder(resp.Body).Decode(result)
+		if err != nil {
+			return retry.Unrecoverable(fmt.Errorf("failed to decode response from zenith api: %w", err))
+		}
+		return nil
+	},
+		retry.Context(ctx),
+		retry.Attempts(c.Retries+1),
+		retry.Delay(c.RetryDelay),
+		retry.DelayType(retry.BackOffDelay),
+		retry.LastErrorOnly(true),
+	)
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		statusCode = statusErr.StatusCode
+	}
+	return statusCode, err
+}
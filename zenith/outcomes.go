@@ -0,0 +1,220 @@
+package zenith
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
+	cosmosSdk "github.com/cosmos/cosmos-sdk/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+	"go.uber.org/zap"
+)
+
+// Outcome of a bid we placed, known once the chain passes the auction height
+const (
+	BidOutcomePending  = "pending"
+	BidOutcomeWon      = "won"      //Our bid won the auction, the TXs are in the auction block
+	BidOutcomeLost     = "lost"     //The auction height passed without our TXs on chain
+	BidOutcomeIncluded = "included" //Our TXs are on chain, but our bid didn't win (e.g. the user's TX was also in the mempool)
+)
+
+// Bids are kept this many days for the stats
+const bidHistoryDays = 30
+
+// A bid Zenith accepted
+type PlacedBid struct {
+	Id               string        //Zenith's bid ID (or the auction height and first TX hash if Zenith didn't return one)
+	Height           int64         //The auction height
+	Kind             string        //either top or block
+	Time             time.Time     //When the bid was placed
+	Amount           cosmosSdk.Int //Total Zenith payments (in the fee denom)
+	SignedTxHashes   []string      //Hashes of the TXs we signed, in bid order
+	ReturnedTxHashes []string      //Hashes Zenith returned for the bid
+	HashMismatch     bool          //Zenith returned different hashes than the ones we signed
+	Outcome          string        //One of the BidOutcome constants
+	Profit           cosmosSdk.Int //Hot wallet profit after the bid and TX fees (in the fee denom), once known
+	ProfitKnown      bool
+}
+
+// Bid stats for a day (UTC) and kind of bid. All amounts are in the fee denom.
+type DailyBidStats struct {
+	Date           string //YYYY-MM-DD
+	Kind           string //top or block
+	Bids           int
+	Won            int
+	Lost           int
+	Included       int
+	Pending        int
+	HashMismatches int           //Bids Zenith returned different TX hashes for
+	WinRate        float64       //Bids won out of the bids with a known outcome
+	TotalBid       cosmosSdk.Int //Sum of the bid amounts
+	AverageBid     cosmosSdk.Int
+	ProfitAfterBid cosmosSdk.Int //Hot wallet profit after the bid and TX fees, for the won and included bids
+}
+
+// Key: bid ID, Value: *PlacedBid
+var placedBids = map[string]*PlacedBid{}
+var placedBidsLock sync.Mutex
+
+// Track a bid Zenith accepted. The TXs are the ones we signed for the bid.
+// Returns the ID the bid is tracked by, see RecordBidOutcome.
+func TrackBid(bidReq *ZenithBidRequest, bidResp *BidResponse, txs [][]byte, amount cosmosSdk.Int) string {
+	bid := &PlacedBid{
+		Id:               bidResp.Id,
+		Height:           bidReq.Height,
+		Kind:             BidKindTop,
+		Time:             time.Now(),
+		Amount:           amount,
+		SignedTxHashes:   []string{},
+		ReturnedTxHashes: bidResp.TxHashes,
+		Outcome:          BidOutcomePending,
+		Profit:           cosmosSdk.ZeroInt(),
+	}
+
+	if bidReq.Kind != "" {
+		bid.Kind = bidReq.Kind
+	}
+
+	for _, txBytes := range txs {
+		bid.SignedTxHashes = append(bid.SignedTxHashes, fmt.Sprintf("%X", tmtypes.Tx(txBytes).Hash()))
+	}
+
+	//Zenith should return the hashes of the TXs we signed, anything else means the bid isn't what we think it is
+	bid.HashMismatch = !sameHashes(bid.SignedTxHashes, bid.ReturnedTxHashes)
+	if bid.HashMismatch {
+		config.Logger.Warn("Zenith bid TX hashes don't match the signed TXs",
+			zap.Int64("height", bid.Height),
+			zap.String("bid id", bid.Id),
+			zap.Strings("signed", bid.SignedTxHashes),
+			zap.Strings("returned", bid.ReturnedTxHashes),
+		)
+	}
+
+	if bid.Id == "" && len(bid.SignedTxHashes) > 0 {
+		bid.Id = fmt.Sprintf("%d-%s", bid.Height, bid.SignedTxHashes[0])
+	}
+
+	placedBidsLock.Lock()
+	defer placedBidsLock.Unlock()
+	for id, placed := range placedBids {
+		if time.Since(placed.Time) > bidHistoryDays*24*time.Hour {
+			delete(placedBids, id)
+		}
+	}
+	placedBids[bid.Id] = bid
+	return bid.Id
+}
+
+func sameHashes(signed []string, returned []string) bool {
+	if len(signed) != len(returned) {
+		return false
+	}
+	for i := range signed {
+		if !strings.EqualFold(signed[i], returned[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// A copy of the tracked bid
+func GetPlacedBid(id string) (PlacedBid, bool) {
+	placedBidsLock.Lock()
+	defer placedBidsLock.Unlock()
+	bid, ok := placedBids[id]
+	if !ok {
+		return PlacedBid{}, false
+	}
+	return *bid, true
+}
+
+// Record the outcome of a pending bid. Won and lost top of block bids are passed to the bid strategy.
+// Bids included without winning aren't, since the bid amount had nothing to do with it. Neither are block bids,
+// which compete in a different auction and are priced off the strategy's top of block bid.
+func RecordBidOutcome(id string, outcome string) {
+	placedBidsLock.Lock()
+	bid, ok := placedBids[id]
+	if !ok || bid.Outcome != BidOutcomePending {
+		placedBidsLock.Unlock()
+		return
+	}
+	bid.Outcome = outcome
+	placedBidsLock.Unlock()
+
+	config.Logger.Info("Zenith bid outcome", zap.Int64("height", bid.Height), zap.String("bid id", id), zap.String("kind", bid.Kind), zap.String("outcome", outcome), zap.String("amount", bid.Amount.String()))
+
+	if bid.Kind != BidKindTop || (outcome != BidOutcomeWon && outcome != BidOutcomeLost) {
+		return
+	}
+	strategy, err := GetBidStrategy()
+	if err == nil {
+		strategy.RecordOutcome(outcome == BidOutcomeWon)
+	}
+}
+
+// Record the hot wallet's profit after the bid and TX fees (in the fee denom, may be negative).
+// Called once for each trade in the bid, the bid's profit is the total.
+func RecordBidProfit(id string, profit cosmosSdk.Int) {
+	placedBidsLock.Lock()
+	defer placedBidsLock.Unlock()
+	if bid, ok := placedBids[id]; ok {
+		bid.Profit = bid.Profit.Add(profit)
+		bid.ProfitKnown = true
+	}
+}
+
+// Stats for each day and kind of bid, most recent day first (top of block before block bids on the same day)
+func GetBidStats() []DailyBidStats {
+	placedBidsLock.Lock()
+	defer placedBidsLock.Unlock()
+
+	days := map[string]*DailyBidStats{}
+	for _, bid := range placedBids {
+		date := bid.Time.UTC().Format("2006-01-02")
+		day, ok := days[date+"/"+bid.Kind]
+		if !ok {
+			day = &DailyBidStats{Date: date, Kind: bid.Kind, TotalBid: cosmosSdk.ZeroInt(), AverageBid: cosmosSdk.ZeroInt(), ProfitAfterBid: cosmosSdk.ZeroInt()}
+			days[date+"/"+bid.Kind] = day
+		}
+
+		day.Bids++
+		day.TotalBid = day.TotalBid.Add(bid.Amount)
+		if bid.HashMismatch {
+			day.HashMismatches++
+		}
+		if bid.ProfitKnown {
+			day.ProfitAfterBid = day.ProfitAfterBid.Add(bid.Profit)
+		}
+
+		switch bid.Outcome {
+		case BidOutcomeWon:
+			day.Won++
+		case BidOutcomeLost:
+			day.Lost++
+		case BidOutcomeIncluded:
+			day.Included++
+		default:
+			day.Pending++
+		}
+	}
+
+	stats := []DailyBidStats{}
+	for _, day := range days {
+		day.AverageBid = day.TotalBid.QuoRaw(int64(day.Bids))
+		if known := day.Won + day.Lost + day.Included; known > 0 {
+			day.WinRate = float64(day.Won) / float64(known)
+		}
+		stats = append(stats, *day)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Date != stats[j].Date {
+			return stats[i].Date > stats[j].Date
+		}
+		return stats[i].Kind == BidKindTop && stats[j].Kind != BidKindTop
+	})
+	return stats
+}
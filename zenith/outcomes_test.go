@@ -0,0 +1,108 @@
+package zenith_test
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/DefiantLabs/RedpointSwap/zenith"
+	"github.com/DefiantLabs/RedpointSwap/zenith/zenithtest"
+	cosmosSdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Records the outcomes passed to the strategy
+type recordingStrategy struct {
+	zenith.FixedStrategy
+	outcomes []bool
+}
+
+func (s *recordingStrategy) RecordOutcome(won bool) {
+	s.outcomes = append(s.outcomes, won)
+}
+
+func TestBidOutcomes(t *testing.T) {
+	server := zenithtest.NewServer(chainID)
+	defer server.Close()
+	client := server.Client()
+
+	strategy := &recordingStrategy{}
+	zenith.SetBidStrategy(strategy)
+	defer zenith.SetBidStrategy(nil)
+
+	placeBid := func(height int64, amount int64) string {
+		txs := [][]byte{[]byte("user tx"), []byte("arbitrage tx " + time.Now().String())}
+		bidReq := &zenith.ZenithBidRequest{ChainID: chainID, Height: height}
+		for _, tx := range txs {
+			bidReq.Txs = append(bidReq.Txs, b64.StdEncoding.EncodeToString(tx))
+		}
+
+		bidResp, err := client.PlaceBid(context.Background(), bidReq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return zenith.TrackBid(bidReq, bidResp, txs, cosmosSdk.NewInt(amount))
+	}
+
+	won := placeBid(101, 1000)
+	lost := placeBid(102, 2000)
+	included := placeBid(103, 3000)
+	pending := placeBid(104, 4000)
+
+	bid, ok := zenith.GetPlacedBid(won)
+	if !ok || bid.HashMismatch || bid.Outcome != zenith.BidOutcomePending || len(bid.SignedTxHashes) != 2 {
+		t.Fatalf("unexpected tracked bid %+v", bid)
+	}
+
+	zenith.RecordBidOutcome(won, zenith.BidOutcomeWon)
+	zenith.RecordBidOutcome(lost, zenith.BidOutcomeLost)
+	zenith.RecordBidOutcome(included, zenith.BidOutcomeIncluded)
+	zenith.RecordBidOutcome(won, zenith.BidOutcomeLost) //Outcomes are only recorded once
+	zenith.RecordBidProfit(won, cosmosSdk.NewInt(5000))
+	zenith.RecordBidProfit(included, cosmosSdk.NewInt(-500))
+
+	//Included bids didn't win or lose the auction
+	if len(strategy.outcomes) != 2 || !strategy.outcomes[0] || strategy.outcomes[1] {
+		t.Errorf("expected the strategy to get a win and a loss, got %v", strategy.outcomes)
+	}
+	if bid, _ := zenith.GetPlacedBid(pending); bid.Outcome != zenith.BidOutcomePending {
+		t.Errorf("expected bid %s to be pending, got %s", pending, bid.Outcome)
+	}
+
+	stats := zenith.GetBidStats()
+	if len(stats) != 1 {
+		t.Fatalf("expected stats for one day, got %+v", stats)
+	}
+
+	day := stats[0]
+	if day.Date != time.Now().UTC().Format("2006-01-02") || day.Bids != 4 || day.Won != 1 || day.Lost != 1 || day.Included != 1 || day.Pending != 1 {
+		t.Errorf("unexpected bid counts %+v", day)
+	}
+	if day.WinRate < 0.33 || day.WinRate > 0.34 {
+		t.Errorf("expected a win rate of 1/3, got %f", day.WinRate)
+	}
+	if !day.AverageBid.Equal(cosmosSdk.NewInt(2500)) || !day.ProfitAfterBid.Equal(cosmosSdk.NewInt(4500)) {
+		t.Errorf("expected an average bid of 2500 and profit of 4500, got %s and %s", day.AverageBid, day.ProfitAfterBid)
+	}
+
+	//Zenith didn't return an ID or the hashes of our TXs, so the bid is tracked by its height and first TX
+	txs := [][]byte{[]byte("user tx"), []byte("arbitrage tx")}
+	bidReq := &zenith.ZenithBidRequest{ChainID: chainID, Height: 201}
+	bidResp := &zenith.BidResponse{ChainID: chainID, Height: 201, TxHashes: []string{"ABC", "DEF"}}
+	bid, ok = zenith.GetPlacedBid(zenith.TrackBid(bidReq, bidResp, txs, cosmosSdk.NewInt(100)))
+	if !ok || !bid.HashMismatch || bid.Id != "201-"+bid.SignedTxHashes[0] {
+		t.Errorf("expected a hash mismatch for bid %+v", bid)
+	}
+
+	//Block bids are a different auction, so their outcomes don't adjust the strategy and they have their own stats
+	blockReq := &zenith.ZenithBidRequest{ChainID: chainID, Height: 202, Kind: zenith.BidKindBlock}
+	block := zenith.TrackBid(blockReq, &zenith.BidResponse{Id: "block bid"}, txs, cosmosSdk.NewInt(100))
+	zenith.RecordBidOutcome(block, zenith.BidOutcomeWon)
+	if len(strategy.outcomes) != 2 {
+		t.Errorf("expected the block bid outcome to be left out of the strategy, got %v", strategy.outcomes)
+	}
+	stats = zenith.GetBidStats()
+	if len(stats) != 2 || stats[0].Kind != zenith.BidKindTop || stats[1].Kind != zenith.BidKindBlock || stats[1].Won != 1 {
+		t.Errorf("expected separate top and block stats, got %+v", stats)
+	}
+}
@@ -1,42 +1,207 @@
-package zenith
+package zenith_test
 
 import (
-	"fmt"
+	"context"
+	b64 "encoding/base64"
+	"errors"
+	"net/http"
+	"os"
 	"testing"
+	"time"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/DefiantLabs/RedpointSwap/zenith"
+	"github.com/DefiantLabs/RedpointSwap/zenith/zenithtest"
+	"go.uber.org/zap"
 )
 
+const chainID = "osmosis-1"
+
+var payments = []zenith.PaymentResponse{
+	{Address: "osmo1validatorpayment", Allocation: 0.9, Denom: "uosmo"},
+	{Address: "osmo1mekatekpayment", Allocation: 0.1, Denom: "uosmo"},
+}
+
+func TestMain(m *testing.M) {
+	config.Logger = zap.NewNop()
+	config.Conf.Api.ChainID = chainID
+	os.Exit(m.Run())
+}
+
 func TestAuction(t *testing.T) {
-	height := int64(7764360)
-	done := false
+	server := zenithtest.NewServer(chainID)
+	defer server.Close()
+	server.SetAuction(101, payments...)
+	server.SetAuctionStatus(99, http.StatusGone)
+	server.SetAuctionStatus(110, http.StatusTooEarly)
 
-	for {
-		req := &AuctionRequest{
-			ChainID: "osmosis-1",
-			Height:  height,
-		}
+	tests := []struct {
+		height   int64
+		expected zenith.ZenithResponse
+	}{
+		{99, zenith.PastAuction},
+		{100, zenith.NotZenithAuction},
+		{101, zenith.ZenithAuction},
+		{110, zenith.AuctionTooFarInFuture},
+	}
 
-		auctionResp, zenithCode, err := req.getAvailableAuction("http://api.mekatek.xyz/v0/auction")
+	client := server.Client()
+	for _, test := range tests {
+		auctionResp, zenithCode, err := client.GetAuction(context.Background(), &zenith.AuctionRequest{ChainID: chainID, Height: test.height})
 		if err != nil {
-			t.Fail()
+			t.Fatalf("height %d: %s", test.height, err.Error())
+		} else if zenithCode != test.expected {
+			t.Errorf("height %d: expected zenith response %d, got %d", test.height, test.expected, zenithCode)
 		}
 
-		zenithStatus := ""
-		if zenithCode == ZenithAuction {
-			zenithStatus = fmt.Sprintf("Auction %d is a zenith auction!", height)
-		} else if zenithCode == PastAuction {
-			zenithStatus = fmt.Sprintf("Auction %d is past", height)
-		} else if zenithCode == AuctionTooFarInFuture {
-			zenithStatus = fmt.Sprintf("Auction %d is too far in the future", height)
-			done = true
-		} else if zenithCode == NotZenithAuction {
-			zenithStatus = fmt.Sprintf("Auction %d is not a zenith auction", height)
+		if zenithCode == zenith.ZenithAuction && (auctionResp == nil || len(auctionResp.Payments) != 2 || !auctionResp.Validate()) {
+			t.Errorf("height %d: unexpected auction %+v", test.height, auctionResp)
 		}
+	}
+}
 
-		fmt.Printf("Resp: %+v, zenithStatus: %s, err: %s\n", auctionResp, zenithStatus, err)
-		height += 1
-		if done {
-			break
-		}
+func TestAuctionRetries(t *testing.T) {
+	server := zenithtest.NewServer(chainID)
+	defer server.Close()
+	server.SetAuction(101, payments...)
+	client := server.Client()
+
+	//Two retries are enough to get past two server errors
+	server.FailNext(2, http.StatusServiceUnavailable)
+	_, zenithCode, err := client.GetAuction(context.Background(), &zenith.AuctionRequest{ChainID: chainID, Height: 101})
+	if err != nil || zenithCode != zenith.ZenithAuction {
+		t.Fatalf("expected a zenith auction after retries, got %d (err: %v)", zenithCode, err)
+	} else if server.Requests() != 3 {
+		t.Errorf("expected 3 requests, got %d", server.Requests())
 	}
 
+	//But not three
+	server.FailNext(3, http.StatusBadGateway)
+	_, zenithCode, err = client.GetAuction(context.Background(), &zenith.AuctionRequest{ChainID: chainID, Height: 101})
+	var statusErr *zenith.StatusError
+	if zenithCode != zenith.QueryError || !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected a query error with status 502, got %d (err: %v)", zenithCode, err)
+	}
+
+	//Client errors are not retried
+	requests := server.Requests()
+	_, zenithCode, err = client.GetAuction(context.Background(), &zenith.AuctionRequest{ChainID: "wrong-chain", Height: 101})
+	if zenithCode != zenith.QueryError || err == nil {
+		t.Errorf("expected a query error for the wrong chain, got %d (err: %v)", zenithCode, err)
+	} else if server.Requests() != requests+1 {
+		t.Errorf("expected 1 request for a bad request, got %d", server.Requests()-requests)
+	}
+}
+
+func TestAuctionTimeout(t *testing.T) {
+	server := zenithtest.NewServer(chainID)
+	defer server.Close()
+	server.SetAuction(101, payments...)
+	server.SetDelay(200 * time.Millisecond)
+
+	client := zenith.NewHttpClient(server.AuctionUrl(), server.BidUrl(), 20*time.Millisecond, 0)
+	_, zenithCode, err := client.GetAuction(context.Background(), &zenith.AuctionRequest{ChainID: chainID, Height: 101})
+	if zenithCode != zenith.QueryError || err == nil {
+		t.Errorf("expected the request to time out, got %d (err: %v)", zenithCode, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, zenithCode, err = server.Client().GetAuction(ctx, &zenith.AuctionRequest{ChainID: chainID, Height: 101})
+	if zenithCode != zenith.QueryError || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a canceled request, got %d (err: %v)", zenithCode, err)
+	}
+}
+
+func TestBid(t *testing.T) {
+	server := zenithtest.NewServer(chainID)
+	defer server.Close()
+	client := server.Client()
+
+	bidReq := &zenith.ZenithBidRequest{
+		ChainID: chainID,
+		Height:  101,
+		Txs:     []string{b64.StdEncoding.EncodeToString([]byte("user tx")), b64.StdEncoding.EncodeToString([]byte("arbitrage tx"))},
+	}
+
+	bidResp, err := client.PlaceBid(context.Background(), bidReq)
+	if err != nil {
+		t.Fatal(err)
+	} else if bidResp.Id == "" || len(bidResp.TxHashes) != 2 || bidResp.Height != 101 {
+		t.Fatalf("unexpected bid response %+v", bidResp)
+	} else if bids := server.Bids(); len(bids) != 1 || bids[0].Id != bidResp.Id {
+		t.Fatalf("expected the server to record the bid, got %+v", bids)
+	}
+
+	statusReq := &zenith.BidStatusRequest{ChainID: chainID, Height: 101, Id: bidResp.Id}
+	status, err := client.GetBidStatus(context.Background(), statusReq)
+	if err != nil || status.Status != zenith.BidStatusPending {
+		t.Errorf("expected a pending bid, got %+v (err: %v)", status, err)
+	}
+
+	server.SetBidOutcome(101, zenith.BidStatusWon)
+	status, err = client.GetBidStatus(context.Background(), statusReq)
+	if err != nil || status.Status != zenith.BidStatusWon {
+		t.Errorf("expected a winning bid, got %+v (err: %v)", status, err)
+	}
+	if winningBid, ok := server.WinningBid(101); !ok || winningBid.Id != bidResp.Id {
+		t.Errorf("expected our bid to land at the auction height, got %+v", winningBid)
+	}
+	if _, ok := server.WinningBid(102); ok {
+		t.Error("expected no winning bid at a height without a scripted outcome")
+	}
+
+	_, err = client.GetBidStatus(context.Background(), &zenith.BidStatusRequest{ChainID: chainID, Height: 101, Id: "unknown"})
+	var statusErr *zenith.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown bid, got %v", err)
+	}
+
+	server.SetBidStatusCode(http.StatusBadRequest)
+	_, err = client.PlaceBid(context.Background(), bidReq)
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a rejected bid, got %v", err)
+	} else if len(server.Bids()) != 1 {
+		t.Errorf("rejected bid should not be recorded")
+	}
+}
+
+func TestZenithBlockNotificationHandler(t *testing.T) {
+	server := zenithtest.NewServer(chainID)
+	defer server.Close()
+	zenith.SetClient(server.Client())
+	defer zenith.SetClient(nil)
+
+	server.SetAuction(102, payments...)
+	server.SetAuctionStatus(104, http.StatusTooEarly)
+
+	zenith.ZenithBlockNotificationHandler(100, 6000)
+
+	zBlocks := zenith.GetZenithBlocks()
+	if len(zBlocks) != 1 || zBlocks[0].Height != 102 || zBlocks[0].MillisecondsUntilBlock != 12000 {
+		t.Fatalf("expected one zenith block at height 102, got %+v", zBlocks)
+	} else if zBlocks[0].Auction == nil || !zBlocks[0].Auction.Validate() {
+		t.Errorf("expected the zenith block to have the auction payments, got %+v", zBlocks[0].Auction)
+	}
+
+	//Known heights aren't queried again, and neither is the height that was too far in the future (until the block time passes)
+	requests := server.Requests()
+	zenith.ZenithBlockNotificationHandler(100, 6000)
+	if server.Requests() != requests {
+		t.Errorf("expected no new requests, got %d", server.Requests()-requests)
+	}
+
+	//A slow Zenith is cut off halfway to the next block, and the lookahead backs off
+	server.SetDelay(time.Second)
+	start := time.Now()
+	zenith.ZenithBlockNotificationHandler(101, 200)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the lookahead to stop after 100ms, took %s", elapsed)
+	}
+
+	requests = server.Requests()
+	zenith.ZenithBlockNotificationHandler(101, 200)
+	if server.Requests() != requests {
+		t.Errorf("expected no requests while backing off, got %d", server.Requests()-requests)
+	}
 }
@@ -0,0 +1,188 @@
+package zenith
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/DefiantLabs/RedpointSwap/osmosis"
+	cosmosSdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Names of the built in bid strategies (see config BidStrategy)
+const (
+	PercentageBidStrategy = "percentage"
+	FixedBidStrategy      = "fixed"
+	AdaptiveBidStrategy   = "adaptive"
+)
+
+// Prices our bids for Zenith blocks. The bid is still capped at the configured MaximumBidAmount,
+// and arbitrage swaps that don't profit after their share of the bid are left out of it.
+type BidStrategy interface {
+	Name() string //Reported in each trade's status
+	// The bid (in the fee denom) for arbitrage swaps with the given estimated revenue (in the fee denom).
+	// timeLeft is how long the request has before it expires, 0 if unknown.
+	Bid(revenue cosmosSdk.Int, timeLeft time.Duration) cosmosSdk.Int
+	// Called with the outcome of each bid we placed
+	RecordOutcome(won bool)
+}
+
+// Bids a fixed percentage of the revenue
+type PercentageStrategy struct {
+	Percentage cosmosSdk.Dec
+}
+
+func (s *PercentageStrategy) Name() string {
+	return fmt.Sprintf("%s (%s of revenue)", PercentageBidStrategy, percentString(s.Percentage))
+}
+
+func (s *PercentageStrategy) Bid(revenue cosmosSdk.Int, _ time.Duration) cosmosSdk.Int {
+	return revenue.ToDec().Mul(s.Percentage).TruncateInt()
+}
+
+func (s *PercentageStrategy) RecordOutcome(_ bool) {}
+
+// Always bids the same amount
+type FixedStrategy struct {
+	Amount cosmosSdk.Int
+}
+
+func (s *FixedStrategy) Name() string {
+	return fmt.Sprintf("%s (%s%s)", FixedBidStrategy, s.Amount, osmosis.FeeDenom)
+}
+
+func (s *FixedStrategy) Bid(_ cosmosSdk.Int, _ time.Duration) cosmosSdk.Int {
+	return s.Amount
+}
+
+func (s *FixedStrategy) RecordOutcome(_ bool) {}
+
+// Bids a percentage of the revenue that goes up by Step after each lost auction and down by Step after each win,
+// staying between MinPercentage and MaxPercentage. Requests about to expire bid MaxPercentage.
+type AdaptiveStrategy struct {
+	MinPercentage cosmosSdk.Dec
+	MaxPercentage cosmosSdk.Dec
+	Step          cosmosSdk.Dec
+	UrgentTime    time.Duration //Requests with less time than this left bid MaxPercentage
+
+	mu         sync.Mutex
+	percentage cosmosSdk.Dec
+}
+
+func NewAdaptiveStrategy(initial, min, max, step cosmosSdk.Dec, urgentTime time.Duration) (*AdaptiveStrategy, error) {
+	if min.IsNegative() || max.GTE(cosmosSdk.OneDec()) || min.GT(max) || !step.IsPositive() {
+		return nil, errors.New("adaptive bid percentages must be 0 <= min <= max < 1, with a positive step")
+	}
+
+	s := &AdaptiveStrategy{MinPercentage: min, MaxPercentage: max, Step: step, UrgentTime: urgentTime}
+	s.percentage = s.clamp(initial)
+	return s, nil
+}
+
+func (s *AdaptiveStrategy) Name() string {
+	return fmt.Sprintf("%s (%s of revenue)", AdaptiveBidStrategy, percentString(s.Percentage()))
+}
+
+// The percentage of the revenue we currently bid
+func (s *AdaptiveStrategy) Percentage() cosmosSdk.Dec {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.percentage
+}
+
+func (s *AdaptiveStrategy) Bid(revenue cosmosSdk.Int, timeLeft time.Duration) cosmosSdk.Int {
+	percentage := s.Percentage()
+	if timeLeft > 0 && timeLeft < s.UrgentTime {
+		percentage = s.MaxPercentage
+	}
+	return revenue.ToDec().Mul(percentage).TruncateInt()
+}
+
+func (s *AdaptiveStrategy) RecordOutcome(won bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if won {
+		s.percentage = s.clamp(s.percentage.Sub(s.Step))
+	} else {
+		s.percentage = s.clamp(s.percentage.Add(s.Step))
+	}
+}
+
+func (s *AdaptiveStrategy) clamp(percentage cosmosSdk.Dec) cosmosSdk.Dec {
+	if percentage.LT(s.MinPercentage) {
+		return s.MinPercentage
+	} else if percentage.GT(s.MaxPercentage) {
+		return s.MaxPercentage
+	}
+	return percentage
+}
+
+var bidStrategy BidStrategy
+var bidStrategyLock sync.Mutex
+
+// The configured bid strategy, unless another strategy was set with SetBidStrategy
+func GetBidStrategy() (BidStrategy, error) {
+	bidStrategyLock.Lock()
+	defer bidStrategyLock.Unlock()
+
+	if bidStrategy == nil {
+		strategy, err := NewBidStrategy(config.Conf)
+		if err != nil {
+			return nil, err
+		}
+		bidStrategy = strategy
+	}
+	return bidStrategy, nil
+}
+
+func SetBidStrategy(strategy BidStrategy) {
+	bidStrategyLock.Lock()
+	defer bidStrategyLock.Unlock()
+	bidStrategy = strategy
+}
+
+// The bid strategy for the config's BidStrategy (percentage if not set)
+func NewBidStrategy(conf config.Config) (BidStrategy, error) {
+	percentage, err := toDec(conf.Zenith.BidPercentage)
+	if err != nil {
+		return nil, errors.New("server misconfiguration (zenith bid percentage), please notify administrator")
+	}
+
+	switch conf.Zenith.BidStrategy {
+	case "", PercentageBidStrategy:
+		return &PercentageStrategy{Percentage: percentage}, nil
+	case FixedBidStrategy:
+		amount, err := cosmosSdk.ParseCoinNormalized(conf.Zenith.FixedBidAmount)
+		if err != nil || amount.Denom != osmosis.FeeDenom || !amount.IsPositive() {
+			return nil, errors.New("server misconfiguration (zenith FixedBidAmount), please notify administrator")
+		}
+		return &FixedStrategy{Amount: amount.Amount}, nil
+	case AdaptiveBidStrategy:
+		min, err1 := toDec(conf.Zenith.AdaptiveMinPercentage)
+		max, err2 := toDec(conf.Zenith.AdaptiveMaxPercentage)
+		step, err3 := toDec(conf.Zenith.AdaptiveStep)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, errors.New("server misconfiguration (zenith adaptive bid percentages), please notify administrator")
+		}
+
+		strategy, err := NewAdaptiveStrategy(percentage, min, max, step, time.Duration(conf.Zenith.AdaptiveUrgentSeconds)*time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("server misconfiguration (%s), please notify administrator", err.Error())
+		}
+		return strategy, nil
+	}
+
+	return nil, fmt.Errorf("server misconfiguration (unknown zenith BidStrategy %s), please notify administrator", conf.Zenith.BidStrategy)
+}
+
+func toDec(f float64) (cosmosSdk.Dec, error) {
+	return cosmosSdk.NewDecFromStr(strconv.FormatFloat(f, 'f', 6, 64))
+}
+
+func percentString(d cosmosSdk.Dec) string {
+	f, _ := d.Float64()
+	return strconv.FormatFloat(f*100, 'f', -1, 64) + "%"
+}
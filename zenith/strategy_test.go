@@ -0,0 +1,91 @@
+package zenith_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DefiantLabs/RedpointSwap/config"
+	"github.com/DefiantLabs/RedpointSwap/zenith"
+	cosmosSdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func TestAdaptiveStrategy(t *testing.T) {
+	strategy, err := zenith.NewAdaptiveStrategy(
+		cosmosSdk.MustNewDecFromStr("0.5"),
+		cosmosSdk.MustNewDecFromStr("0.3"),
+		cosmosSdk.MustNewDecFromStr("0.7"),
+		cosmosSdk.MustNewDecFromStr("0.15"),
+		10*time.Second,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revenue := cosmosSdk.NewInt(1000)
+	bids := []struct {
+		won      bool
+		expected int64
+	}{
+		{false, 650}, //Raised after a loss
+		{false, 700}, //But not above the max
+		{true, 550},  //Lowered after a win
+		{true, 400},
+		{true, 300}, //But not below the min
+	}
+
+	if bid := strategy.Bid(revenue, time.Minute); !bid.Equal(cosmosSdk.NewInt(500)) {
+		t.Fatalf("expected the initial bid to be 500, got %s", bid)
+	}
+	for i, b := range bids {
+		strategy.RecordOutcome(b.won)
+		if bid := strategy.Bid(revenue, time.Minute); !bid.Equal(cosmosSdk.NewInt(b.expected)) {
+			t.Errorf("bid %d: expected %d, got %s", i, b.expected, bid)
+		}
+	}
+
+	//Requests about to expire bid the max
+	if bid := strategy.Bid(revenue, 5*time.Second); !bid.Equal(cosmosSdk.NewInt(700)) {
+		t.Errorf("expected an urgent bid of 700, got %s", bid)
+	}
+}
+
+func TestNewBidStrategy(t *testing.T) {
+	conf := config.Config{}
+	conf.Zenith.BidPercentage = 0.5
+	conf.Zenith.FixedBidAmount = "2500uosmo"
+
+	tests := []struct {
+		strategy string
+		name     string
+		bid      int64
+	}{
+		{"", "percentage (50% of revenue)", 500},
+		{zenith.FixedBidStrategy, "fixed (2500uosmo)", 2500},
+	}
+
+	for _, test := range tests {
+		conf.Zenith.BidStrategy = test.strategy
+		strategy, err := zenith.NewBidStrategy(conf)
+		if err != nil {
+			t.Fatalf("%q: %s", test.strategy, err.Error())
+		} else if strategy.Name() != test.name {
+			t.Errorf("%q: expected name %q, got %q", test.strategy, test.name, strategy.Name())
+		} else if bid := strategy.Bid(cosmosSdk.NewInt(1000), 0); !bid.Equal(cosmosSdk.NewInt(test.bid)) {
+			t.Errorf("%q: expected bid %d, got %s", test.strategy, test.bid, bid)
+		}
+	}
+
+	//Adaptive percentages must be valid
+	conf.Zenith.BidStrategy = zenith.AdaptiveBidStrategy
+	conf.Zenith.AdaptiveMinPercentage = 0.6
+	conf.Zenith.AdaptiveMaxPercentage = 0.4
+	conf.Zenith.AdaptiveStep = 0.05
+	if _, err := zenith.NewBidStrategy(conf); err == nil {
+		t.Error("expected an error for an adaptive min percentage above the max")
+	}
+
+	conf.Zenith.BidStrategy = "unknown"
+	if _, err := zenith.NewBidStrategy(conf); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
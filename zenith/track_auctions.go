@@ -1,6 +1,7 @@
 package zenith
 
 import (
+	"context"
 	"sync"
 	"time"
 
@@ -33,16 +34,37 @@ func GetZenithBlocks() []*FutureBlock {
 	return zBlocks
 }
 
+// Used until we've seen enough blocks to know the block time
+const defaultBlockTime = 6 * time.Second
+
+// Longest the lookahead is put on hold after Zenith errors
+const maxLookaheadBackoff = time.Minute
+
+// Heights Zenith said are too far in the future. Key: int64 block height and Value: time.Time to query the height again after.
+var auctionRetryAfter sync.Map
+
+// Consecutive lookaheads that got Zenith errors, and when the lookahead may run again
+var lookaheadBackoff struct {
+	sync.Mutex
+	failures int
+	until    time.Time
+}
+
 // TODO: notify the admin if the Zenith endpoint stops working
 //
 // This function is called for every new block produced on the chain.
 // We query the Zenith auction endpoint (see https://meka.tech/zenith#get-_v0_auction)
-// for the next 10 blocks (which is the max supported by Zenith).
+// for the next few blocks (see config LookaheadBlocks, at most 10 which is the max supported by Zenith).
+// The heights are queried concurrently and the queries are cut off halfway to the next block, so the auctions are known before we bid.
 //
 // Overall, we are tracking available Zenith blocks using Mekatek's service endpoints.
 // This function only tracks what blocks Zenith will produce -- it does not bid on auctions.
 func ZenithBlockNotificationHandler(lastChainHeight int64, millisecondsBetweenBlocks int64) {
 	conf := config.Conf
+	blockTime := time.Duration(millisecondsBetweenBlocks) * time.Millisecond
+	if blockTime <= 0 {
+		blockTime = defaultBlockTime
+	}
 
 	// Remove any blocks that already happened
 	zenithBlocks.Range(func(key, _ any) bool {
@@ -51,37 +73,107 @@ func ZenithBlockNotificationHandler(lastChainHeight int64, millisecondsBetweenBl
 		}
 		return true
 	})
+	auctionRetryAfter.Range(func(key, _ any) bool {
+		if key.(int64) <= lastChainHeight {
+			auctionRetryAfter.Delete(key)
+		}
+		return true
+	})
+
+	if lookaheadOnHold() {
+		return
+	}
+
+	//Skip heights we already know about, and heights that were too far in the future a moment ago
+	heights := []int64{}
+	for height := lastChainHeight + 1; height <= lastChainHeight+conf.GetZenithLookaheadBlocks(); height++ {
+		if _, ok := zenithBlocks.Load(height); ok {
+			continue
+		}
+		if retryAfter, ok := auctionRetryAfter.Load(height); ok && time.Now().Before(retryAfter.(time.Time)) {
+			continue
+		}
+		heights = append(heights, height)
+	}
 
-	for height := lastChainHeight; height < lastChainHeight+5; height++ {
-		//We have never queried Zenith for the given block
-		if _, ok := zenithBlocks.Load(height); !ok {
+	ctx, cancel := context.WithTimeout(context.Background(), blockTime/2)
+	defer cancel()
 
-			req := &AuctionRequest{
-				ChainID: conf.Api.ChainID,
-				Height:  height,
-			}
+	var wg sync.WaitGroup
+	errs := make(chan error, len(heights))
+	for _, height := range heights {
+		wg.Add(1)
+		go func(height int64) {
+			defer wg.Done()
+			errs <- queryAuction(ctx, height, lastChainHeight, blockTime)
+		}(height)
+	}
+	wg.Wait()
+	close(errs)
 
-			auctionResp, zenithCode, err := req.getAvailableAuction(conf.Zenith.ZenithAuctionUrl)
+	failed := false
+	for err := range errs {
+		failed = failed || err != nil
+	}
+	recordLookahead(failed, blockTime)
+}
 
-			//If there was an error or if the auction is too far in the future, we'll need to requery.
-			//Otherwise, the query succeeded and we need to record whether or not this is a Zenith block.
-			if err == nil && zenithCode != AuctionTooFarInFuture {
-				msUntilBlock := (height - lastChainHeight) * millisecondsBetweenBlocks
+// Queries Zenith for the height and records whether it is a Zenith block.
+// If the auction is too far in the future, the height isn't queried again until the negative cache TTL passes.
+func queryAuction(ctx context.Context, height int64, lastChainHeight int64, blockTime time.Duration) error {
+	req := &AuctionRequest{
+		ChainID: config.Conf.Api.ChainID,
+		Height:  height,
+	}
 
-				zBlock := &FutureBlock{
-					IsZenithBlock:          zenithCode == ZenithAuction,
-					Height:                 height,
-					ProjectedBlocktime:     time.Now().Add(time.Millisecond * time.Duration(msUntilBlock)),
-					MillisecondsUntilBlock: msUntilBlock,
-					Auction:                auctionResp,
-				}
+	auctionResp, zenithCode, err := GetClient().GetAuction(ctx, req)
+	if err != nil {
+		return err
+	} else if zenithCode == AuctionTooFarInFuture {
+		auctionRetryAfter.Store(height, time.Now().Add(config.Conf.GetZenithNegativeCacheTtl(blockTime)))
+		return nil
+	}
 
-				if zBlock.IsZenithBlock {
-					config.Logger.Debug("Zenith block", zap.Int64("Found zenith block at height", zBlock.Height))
-				}
+	msUntilBlock := (height - lastChainHeight) * blockTime.Milliseconds()
+	zBlock := &FutureBlock{
+		IsZenithBlock:          zenithCode == ZenithAuction,
+		Height:                 height,
+		ProjectedBlocktime:     time.Now().Add(time.Millisecond * time.Duration(msUntilBlock)),
+		MillisecondsUntilBlock: msUntilBlock,
+		Auction:                auctionResp,
+	}
 
-				zenithBlocks.Store(height, zBlock)
-			}
-		}
+	if zBlock.IsZenithBlock {
+		config.Logger.Debug("Zenith block", zap.Int64("Found zenith block at height", zBlock.Height))
+	}
+
+	zenithBlocks.Store(height, zBlock)
+	return nil
+}
+
+// Whether Zenith errors put the lookahead on hold
+func lookaheadOnHold() bool {
+	lookaheadBackoff.Lock()
+	defer lookaheadBackoff.Unlock()
+	return time.Now().Before(lookaheadBackoff.until)
+}
+
+// After a lookahead with Zenith errors, hold off for a block time, doubling with each failed lookahead in a row (up to maxLookaheadBackoff)
+func recordLookahead(failed bool, blockTime time.Duration) {
+	lookaheadBackoff.Lock()
+	defer lookaheadBackoff.Unlock()
+
+	if !failed {
+		lookaheadBackoff.failures = 0
+		lookaheadBackoff.until = time.Time{}
+		return
+	}
+
+	lookaheadBackoff.failures++
+	backoff := maxLookaheadBackoff
+	if lookaheadBackoff.failures < 16 && blockTime<<(lookaheadBackoff.failures-1) < maxLookaheadBackoff {
+		backoff = blockTime << (lookaheadBackoff.failures - 1)
 	}
+	lookaheadBackoff.until = time.Now().Add(backoff)
+	config.Logger.Warn("Zenith auction lookahead failed, backing off", zap.Int("failures", lookaheadBackoff.failures), zap.Duration("backoff", backoff))
 }
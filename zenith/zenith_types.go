@@ -1,6 +1,8 @@
 package zenith
 
 import (
+	"time"
+
 	"github.com/DefiantLabs/RedpointSwap/simulator"
 )
 
@@ -33,6 +35,12 @@ type BidRequest struct {
 	SimulatedSwap simulator.SimulatedSwapResult //Info from the simulator. This helps us estimate the proceeds and make an accurate auction bid.
 }
 
+// Kinds of Zenith bid. A top of block bid puts its TXs first in the block, a block bid buys the whole block.
+const (
+	BidKindTop   = "top"
+	BidKindBlock = "block"
+)
+
 type UserZenithRequest struct {
 	Expiration    string                        //the request expires if not executed by this time. Must be RFC3339 formatted.
 	SwapTx        string                        `json:"user_swap"`      //signed base 64 encoded cosmos TX (user's swap only)
@@ -40,6 +48,28 @@ type UserZenithRequest struct {
 	SimulatedSwap simulator.SimulatedSwapResult //Info from the simulator. This helps us estimate the proceeds and make an accurate auction bid.
 }
 
+// How long the request has left at the given time before it expires (0 if the expiration is invalid)
+func (r *UserZenithRequest) TimeLeft(at time.Time) time.Duration {
+	reqExpiration, err := time.Parse(time.RFC3339, r.Expiration)
+	if err != nil {
+		return 0
+	}
+	return reqExpiration.Sub(at)
+}
+
+// The kind of bid the request is placed in, top of block unless it opted into a block bid
+func (r *UserZenithRequest) BidKind() string {
+	if r.Kind == "" {
+		return BidKindTop
+	}
+	return r.Kind
+}
+
+// Whether the kind is one Zenith accepts (empty means top)
+func IsValidBidKind(kind string) bool {
+	return kind == "" || kind == BidKindTop || kind == BidKindBlock
+}
+
 type ZenithBidRequest struct {
 	ChainID string   `json:"chain_id"`
 	Height  int64    `json:"height"`
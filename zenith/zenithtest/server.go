@@ -0,0 +1,300 @@
+// Package zenithtest runs a fake Zenith API so the Zenith flow can be tested without Mekatek's servers.
+package zenithtest
+
+import (
+	b64 "encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DefiantLabs/RedpointSwap/zenith"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+const (
+	AuctionPath = "/v0/auction"
+	BidPath     = "/v0/bid"
+)
+
+// A bid the server received
+type Bid struct {
+	Id       string
+	Request  zenith.ZenithBidRequest
+	TxHashes []string //Hashes of the bid's TXs, as Zenith returns them
+}
+
+// Fake Zenith API. Heights are not auctions (HTTP 417) unless scripted with SetAuction or SetAuctionStatus.
+// Bids are accepted and stay pending until their outcome is scripted with SetBidOutcome.
+type Server struct {
+	*httptest.Server
+
+	mu                   sync.Mutex
+	chainID              string
+	auctions             map[int64][]zenith.PaymentResponse
+	auctionStatus        map[int64]int
+	defaultAuctionStatus int
+	bidStatusCode        int
+	bidOutcomes          map[int64]string
+	bids                 []Bid
+	failNext             int
+	failStatusCode       int
+	delay                time.Duration
+	requests             int
+}
+
+// Starts a fake Zenith API for the chain. Call Close when done.
+func NewServer(chainID string) *Server {
+	s := &Server{
+		chainID:              chainID,
+		auctions:             map[int64][]zenith.PaymentResponse{},
+		auctionStatus:        map[int64]int{},
+		defaultAuctionStatus: http.StatusExpectationFailed,
+		bidStatusCode:        http.StatusOK,
+		bidOutcomes:          map[int64]string{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(AuctionPath, s.handleAuction)
+	mux.HandleFunc(BidPath, s.handleBid)
+	s.Server = httptest.NewServer(s.intercept(mux))
+	return s
+}
+
+func (s *Server) AuctionUrl() string {
+	return s.URL + AuctionPath
+}
+
+func (s *Server) BidUrl() string {
+	return s.URL + BidPath
+}
+
+// A client for this server that retries twice without waiting
+func (s *Server) Client() *zenith.HttpClient {
+	client := zenith.NewHttpClient(s.AuctionUrl(), s.BidUrl(), time.Second, 2)
+	client.RetryDelay = time.Millisecond
+	return client
+}
+
+// The height is a Zenith auction, the winning bid must make these payments
+func (s *Server) SetAuction(height int64, payments ...zenith.PaymentResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auctions[height] = payments
+	s.auctionStatus[height] = http.StatusOK
+}
+
+// Respond to auction queries for the height with the status, e.g. 410 (past), 417 (not an auction) or 425 (too far in the future)
+func (s *Server) SetAuctionStatus(height int64, statusCode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auctionStatus[height] = statusCode
+}
+
+// Status for heights that weren't scripted (417 by default)
+func (s *Server) SetDefaultAuctionStatus(statusCode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultAuctionStatus = statusCode
+}
+
+// Respond to bids with the status. Bids that get a status other than 200 are not recorded.
+func (s *Server) SetBidStatusCode(statusCode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bidStatusCode = statusCode
+}
+
+// Status of the bids for the height, one of the zenith.BidStatus constants
+func (s *Server) SetBidOutcome(height int64, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bidOutcomes[height] = status
+}
+
+// Respond to the next n requests (of any kind) with the status, e.g. to test retries
+func (s *Server) FailNext(n int, statusCode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failNext = n
+	s.failStatusCode = statusCode
+}
+
+// Wait before responding to each request, e.g. to test timeouts
+func (s *Server) SetDelay(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = delay
+}
+
+// Every bid the server accepted, in the order they were placed
+func (s *Server) Bids() []Bid {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Bid{}, s.bids...)
+}
+
+// The last bid placed for the height if its outcome is scripted as won. Its TXs are the ones that land at the auction height.
+func (s *Server) WinningBid(height int64) (Bid, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bidOutcomes[height] != zenith.BidStatusWon {
+		return Bid{}, false
+	}
+
+	for i := len(s.bids) - 1; i >= 0; i-- {
+		if s.bids[i].Request.Height == height {
+			return s.bids[i], true
+		}
+	}
+	return Bid{}, false
+}
+
+// Number of requests the server received (including failed ones)
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		delay := s.delay
+		fail := s.failNext > 0
+		failStatusCode := s.failStatusCode
+		if fail {
+			s.failNext--
+		}
+		s.mu.Unlock()
+
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if fail {
+			writeError(w, failStatusCode, "scripted failure")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleAuction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	chainID := r.URL.Query().Get("chain_id")
+	height, err := strconv.ParseInt(r.URL.Query().Get("height"), 10, 64)
+	if err != nil || chainID != s.chainID {
+		writeError(w, http.StatusBadRequest, "invalid chain_id or height")
+		return
+	}
+
+	s.mu.Lock()
+	statusCode, ok := s.auctionStatus[height]
+	if !ok {
+		statusCode = s.defaultAuctionStatus
+	}
+	payments := s.auctions[height]
+	s.mu.Unlock()
+
+	if statusCode != http.StatusOK {
+		writeError(w, statusCode, http.StatusText(statusCode))
+		return
+	}
+
+	writeJson(w, http.StatusOK, zenith.AuctionResponse{ChainID: chainID, Height: height, Payments: payments})
+}
+
+func (s *Server) handleBid(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		s.placeBid(w, r)
+	case http.MethodGet:
+		s.getBidStatus(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) placeBid(w http.ResponseWriter, r *http.Request) {
+	var bidReq zenith.ZenithBidRequest
+	err := json.NewDecoder(r.Body).Decode(&bidReq)
+	if err != nil || bidReq.ChainID != s.chainID || len(bidReq.Txs) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid bid")
+		return
+	}
+
+	txHashes := []string{}
+	for _, tx := range bidReq.Txs {
+		txBytes, err := b64.StdEncoding.DecodeString(tx)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "TXs must be base 64 encoded")
+			return
+		}
+		txHashes = append(txHashes, fmt.Sprintf("%X", tmtypes.Tx(txBytes).Hash()))
+	}
+
+	s.mu.Lock()
+	statusCode := s.bidStatusCode
+	bid := Bid{Id: fmt.Sprintf("bid-%d", len(s.bids)+1), Request: bidReq, TxHashes: txHashes}
+	if statusCode == http.StatusOK {
+		s.bids = append(s.bids, bid)
+	}
+	s.mu.Unlock()
+
+	if statusCode != http.StatusOK {
+		writeError(w, statusCode, http.StatusText(statusCode))
+		return
+	}
+
+	writeJson(w, http.StatusOK, zenith.BidResponse{
+		ChainID:  bidReq.ChainID,
+		Height:   bidReq.Height,
+		Kind:     bidReq.Kind,
+		TxHashes: txHashes,
+		Id:       bid.Id,
+	})
+}
+
+func (s *Server) getBidStatus(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, bid := range s.bids {
+		if bid.Id != id {
+			continue
+		}
+
+		status, ok := s.bidOutcomes[bid.Request.Height]
+		if !ok {
+			status = zenith.BidStatusPending
+		}
+		writeJson(w, http.StatusOK, zenith.BidStatusResponse{ChainID: bid.Request.ChainID, Height: bid.Request.Height, Id: bid.Id, Status: status})
+		return
+	}
+
+	writeError(w, http.StatusNotFound, "bid not found")
+}
+
+func writeJson(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, msg string) {
+	writeJson(w, statusCode, map[string]string{"error": msg})
+}